@@ -0,0 +1,71 @@
+package client
+
+import (
+	"strings"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// QueryBuilder builds a standard SQL query quoting identifiers and parameterizing values
+type QueryBuilder struct {
+	columns []string
+	table   string
+	wheres  []string
+	params  []*bigquery.QueryParameter
+}
+
+// NewQueryBuilder generates an empty query builder
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// Select adds columns to select
+// All columns are selected with * or without columns
+func (b *QueryBuilder) Select(columns ...string) *QueryBuilder {
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// From sets a table to select from like table, dataset.table or project.dataset.table
+func (b *QueryBuilder) From(table string) *QueryBuilder {
+	b.table = table
+	return b
+}
+
+// Where adds a condition with ? placeholders for given values
+// Values are sent as positional parameters, and conditions are joined by AND
+func (b *QueryBuilder) Where(expr string, args ...interface{}) *QueryBuilder {
+	b.wheres = append(b.wheres, expr)
+	for _, arg := range args {
+		b.params = append(b.params, newQueryParameter("", arg))
+	}
+	return b
+}
+
+// Build returns a query string and positional parameters for values of conditions
+// Client.QueryFromBuilder issues a query with them
+func (b *QueryBuilder) Build() (string, []*bigquery.QueryParameter) {
+	columns := []string{"*"}
+	if len(b.columns) > 0 {
+		columns = make([]string, 0, len(b.columns))
+		for _, column := range b.columns {
+			if column == "*" {
+				columns = append(columns, column)
+				continue
+			}
+			columns = append(columns, QuoteIdentifier(column))
+		}
+	}
+
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM " + QuoteIdentifier(b.table)
+	if len(b.wheres) > 0 {
+		query += " WHERE (" + strings.Join(b.wheres, ") AND (") + ")"
+	}
+	return query, b.params
+}
+
+// QuoteIdentifier quotes a given identifier like a column or table name with backticks for standard SQL
+func QuoteIdentifier(name string) string {
+	r := strings.NewReplacer("\\", "\\\\", "`", "\\`")
+	return "`" + r.Replace(name) + "`"
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	Convey("Given a query builder with columns, a table and conditions", t, func() {
+		b := NewQueryBuilder().
+			Select("name", "user-id", "weird`column").
+			From("winter_test00.bq_test.test").
+			Where("age > ? AND score < ?", 20, 0.5).
+			Where("name = ?", "O'Reilly").
+			Where("created_at >= ? AND is_deleted = ?", time.Date(2015, 2, 3, 4, 5, 6, 0, time.UTC), false)
+
+		Convey("When build a query", func() {
+			query, params := b.Build()
+
+			Convey("Then identifiers are quoted and values are parameterized", func() {
+				So(query, ShouldEqual, "SELECT `name`, `user-id`, `weird\\`column` FROM `winter_test00.bq_test.test` "+
+					"WHERE (age > ? AND score < ?) AND (name = ?) AND (created_at >= ? AND is_deleted = ?)")
+				So(params, ShouldResemble, []*bigquery.QueryParameter{
+					{ParameterType: &bigquery.QueryParameterType{Type: "INT64"}, ParameterValue: &bigquery.QueryParameterValue{Value: "20"}},
+					{ParameterType: &bigquery.QueryParameterType{Type: "FLOAT64"}, ParameterValue: &bigquery.QueryParameterValue{Value: "0.5"}},
+					{ParameterType: &bigquery.QueryParameterType{Type: "STRING"}, ParameterValue: &bigquery.QueryParameterValue{Value: "O'Reilly"}},
+					{ParameterType: &bigquery.QueryParameterType{Type: "TIMESTAMP"}, ParameterValue: &bigquery.QueryParameterValue{Value: "2015-02-03 04:05:06 UTC"}},
+					{ParameterType: &bigquery.QueryParameterType{Type: "BOOL"}, ParameterValue: &bigquery.QueryParameterValue{Value: "false"}},
+				})
+			})
+		})
+	})
+
+	Convey("Given a query builder without columns", t, func() {
+		b := NewQueryBuilder().From("test")
+
+		Convey("When build a query", func() {
+			query, params := b.Build()
+
+			Convey("Then all columns are selected", func() {
+				So(query, ShouldEqual, "SELECT * FROM `test`")
+				So(params, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	Convey("Given identifiers containing special characters", t, func() {
+		Convey("When quote them", func() {
+			Convey("Then backticks and backslashes are escaped", func() {
+				So(QuoteIdentifier("user name"), ShouldEqual, "`user name`")
+				So(QuoteIdentifier("a`; DROP TABLE test; --"), ShouldEqual, "`a\\`; DROP TABLE test; --`")
+				So(QuoteIdentifier(`a\`), ShouldEqual, "`a\\\\`")
+			})
+		})
+	})
+}
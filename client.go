@@ -1,29 +1,63 @@
 package client
 
 import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
+	"net/http"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
 
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"golang.org/x/oauth2/jwt"
 )
 
 const (
-	googleTokenURL  = "https://accounts.google.com/o/oauth2/token"
-	defaultPageSize = 5000
-
-	fieldTypeString    = "STRING"
-	fieldTypeInteger   = "INTEGER"
-	fieldTypeFloat     = "FLOAT"
-	fieldTypeBoolean   = "BOOLEAN"
-	fieldTypeRecord    = "RECORD"
-	fieldTypeTimestamp = "TIMESTAMP"
+	googleTokenURL         = "https://accounts.google.com/o/oauth2/token"
+	defaultPageSize        = 5000
+	defaultInsertBatchSize = 500
+
+	fieldTypeString     = "STRING"
+	fieldTypeInteger    = "INTEGER"
+	fieldTypeFloat      = "FLOAT"
+	fieldTypeBoolean    = "BOOLEAN"
+	fieldTypeRecord     = "RECORD"
+	fieldTypeTimestamp  = "TIMESTAMP"
+	fieldTypeBytes      = "BYTES"
+	fieldTypeGeography  = "GEOGRAPHY"
+	fieldTypeInterval   = "INTERVAL"
+	fieldTypeJSON       = "JSON"
+	fieldTypeDate       = "DATE"
+	fieldTypeDatetime   = "DATETIME"
+	fieldTypeTime       = "TIME"
+	fieldTypeNumeric    = "NUMERIC"
+	fieldTypeBigNumeric = "BIGNUMERIC"
+
+	maxLabels = 64
+)
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+	labelKeyPattern   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	labelValuePattern = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+	jobIDPattern      = regexp.MustCompile(`^[a-zA-Z0-9_-]*$`)
 )
 
 const (
@@ -38,21 +72,74 @@ const (
 	CreateIfNeeded CreateDisp = "CREATE_IF_NEEDED"
 	// CreateNever is option not to create a new table
 	CreateNever CreateDisp = "CREATE_NEVER"
+
+	// PriorityInteractive is option to run a query as soon as possible
+	PriorityInteractive Priority = "INTERACTIVE"
+	// PriorityBatch is option to queue a query until idle resources are available
+	PriorityBatch Priority = "BATCH"
 )
 
 // Client is a client for google bigquery
+// A Client is safe for concurrent queries and inserts by multiple goroutines after it is configured.
+// Settings like Dataset, SetLocation and WithRetry are not guarded and must not be changed while the client is in use,
+// so a client of other settings should be made by Clone instead.
 type Client struct {
 	jwtConfig  *jwt.Config
 	datasetRef *bigquery.DatasetReference
-	service    *bigquery.Service
+	location   string
+	endpoint   string
+	// tokenSource is a source of tokens by Application Default Credentials, used instead of the jwt config
+	tokenSource oauth2.TokenSource
+	// billingProject is a project to run jobs in, which defaults to the project of the dataset
+	billingProject string
+
+	retry        *retryPolicy
+	pollInterval time.Duration
+
+	mu            sync.Mutex
+	httpClient    *http.Client
+	serviceClient *http.Client
+	service       *bigquery.Service
+	converters    []Converter
+	matcher       FieldMatcher
+	queryService  QueryService
+	logger        Logger
+
+	insertBatchSize int
+	// insertSchemas caches schemas of tables validated before inserts, and nil means not fetched yet
+	insertSchemas map[string][]*bigquery.TableFieldSchema
 }
 
 // Query is a query with client
 type Query struct {
-	Client      *Client
-	QueryString string
-	JobConfig   *JobConfiguration
-	size        int64
+	Client         *Client
+	QueryString    string
+	JobConfig      *JobConfiguration
+	projectID      string
+	size           int64
+	limit          int
+	concurrency    int
+	onProgress     func(fetched, total uint64)
+	jobIDPrefix    string
+	priority       Priority
+	labels         map[string]string
+	useCache       *bool
+	useLegacy      *bool
+	maxBilled      int64
+	flatten        *bool
+	lenient        bool
+	ignoreUnknown  bool
+	skipMalformed  bool
+	timeout        time.Duration
+	queryTimeoutMs int64
+	params         []*bigquery.QueryParameter
+	paramMode      string
+	jobRef         *bigquery.JobReference
+	err            error
+
+	// skippedMu guards skipped, which is appended while ExecuteStream converts pages in its goroutine
+	skippedMu sync.Mutex
+	skipped   []int
 }
 
 // WriteDisp expresses create disposition
@@ -61,19 +148,39 @@ type WriteDisp string
 // CreateDisp expresses create disposition
 type CreateDisp string
 
+// Priority expresses query priority
+type Priority string
+
 // JobConfiguration for bigquery client
+// DestinationProject and DestinationDataset override the dataset of the client for the temp table if given
 type JobConfiguration struct {
-	AllowLargeResults bool
-	TempTableName     string
-	WriteDisposition  WriteDisp
-	CreateDisposition CreateDisp
+	AllowLargeResults  bool
+	TempTableName      string
+	DestinationProject string
+	DestinationDataset string
+	WriteDisposition   WriteDisp
+	CreateDisposition  CreateDisp
+	TimePartitioning   *TimePartitioning
+	Clustering         []string
+	// TempTableExpiration sets the expiration of the temp table after the query completes
+	// so that bigquery deletes the table automatically
+	TempTableExpiration time.Duration
+	// UseLegacySQL sets the SQL dialect of the query unless it is set by Query.UseLegacySQL
+	// bigquery runs a query in legacy SQL if it is nil
+	UseLegacySQL *bool
 }
 
 // ResponseData is a data set for response from bigquery
+// TotalRows, NumDmlAffectedRows and Stats are only set on the last data before the channel is closed,
+// and Stats is nil if statistics of the job are not available
 type ResponseData struct {
 	Fields []*bigquery.TableFieldSchema
 	Rows   []*bigquery.TableRow
 	Err    error
+
+	TotalRows          uint64
+	NumDmlAffectedRows int64
+	Stats              *QueryStats
 }
 
 // GetPrivateKeyByPEM gets a byte slice as key from a given PEM file
@@ -94,419 +201,1634 @@ func New(email string, privteKey []byte, subject string) *Client {
 	}
 }
 
-func (c *Client) getService() (*bigquery.Service, error) {
-	if c.jwtConfig == nil {
-		return nil, errors.New("Not initialized")
+// NewFromJSON generates a new client for bigquery with a service account JSON key file
+// The subject is a user to impersonate by domain-wide delegation, and it can be empty
+func NewFromJSON(jsonKey []byte, subject string) (*Client, error) {
+	jwtConfig, err := google.JWTConfigFromJSON(jsonKey, bigquery.BigqueryScope)
+	if err != nil {
+		return nil, err
 	}
+	jwtConfig.Subject = subject
+	return &Client{
+		jwtConfig: jwtConfig,
+	}, nil
+}
 
-	client := c.jwtConfig.Client(oauth2.NoContext)
-	service, err := bigquery.New(client)
-	if err != nil {
+// NewFromJSONKey generates a new client for bigquery with a service account JSON key file without impersonation
+func NewFromJSONKey(jsonKey []byte) (*Client, error) {
+	return NewFromJSON(jsonKey, "")
+}
+
+// NewWithValidation generates a new client like New after validating a given private key
+// A KeyError is returned if the key is not a PEM block of a PKCS#1 or PKCS#8 RSA private key
+func NewWithValidation(email string, privateKey []byte, subject string) (*Client, error) {
+	if err := validatePrivateKey(privateKey); err != nil {
 		return nil, err
 	}
-	c.service = service
-	return service, nil
+	return New(email, privateKey, subject), nil
 }
 
-// Dataset sets a target dataset reference
-func (c *Client) Dataset(projectID string, datasetID string) *Client {
-	c.datasetRef = &bigquery.DatasetReference{
-		DatasetId: datasetID,
-		ProjectId: projectID,
+// validatePrivateKey parses a given PEM encoded private key as jwt does on the first request
+func validatePrivateKey(key []byte) error {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return &KeyError{Err: errors.New("not a PEM block")}
 	}
-	return c
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return &KeyError{Err: err}
+		}
+	}
+	if _, ok := parsed.(*rsa.PrivateKey); !ok {
+		return &KeyError{Err: errors.New("not a RSA private key")}
+	}
+	return nil
 }
 
-// Query issues a new query instance
-func (c *Client) Query(queryString string) *Query {
-	return &Query{
-		Client:      c,
-		QueryString: queryString,
-		size:        defaultPageSize,
+// NewWithDefaultCredentials generates a new client for bigquery with Application Default Credentials
+// Credentials are found from GOOGLE_APPLICATION_CREDENTIALS, gcloud or the metadata server of GCE and GKE
+// BigqueryScope is used when no scopes are given
+func NewWithDefaultCredentials(ctx context.Context, scopes ...string) (*Client, error) {
+	if len(scopes) == 0 {
+		scopes = []string{bigquery.BigqueryScope}
+	}
+
+	creds, err := google.FindDefaultCredentials(ctx, scopes...)
+	if err != nil {
+		return nil, err
 	}
+	// the service is built on the first call so that options like WithEndpoint and WithHTTPClient are applied
+	return &Client{
+		tokenSource: creds.TokenSource,
+	}, nil
 }
 
-// SetJobConfig sets job Configuration
-func (q *Query) SetJobConfig(config *JobConfiguration) *Query {
-	q.JobConfig = config
-	return q
+// getService returns the cached service, building it on the first call
+func (c *Client) getService() (*bigquery.Service, error) {
+	return c.getServiceContext(context.Background())
 }
 
-// Execute execute a given query
-func (q *Query) Execute(result interface{}) error {
-	var fields []*bigquery.TableFieldSchema
-	var rows []*bigquery.TableRow
-	var err error
-	if q.JobConfig != nil {
-		fields, rows, err = q.retrieveRowsWithJobConfig(nil)
-	} else {
-		fields, rows, err = q.retrieveRows(nil)
+// getServiceContext returns the cached service, building it with a given context on the first call
+// The service is shared by later calls, so the context only applies to building it like fetching a token
+func (c *Client) getServiceContext(ctx context.Context) (*bigquery.Service, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.service != nil {
+		return c.service, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+
+	client, err := c.authenticatedClient(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = Convert(fields, rows, result)
+	service, err := bigquery.New(client)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	if c.endpoint != "" {
+		service.BasePath = c.endpoint
+	}
+	c.serviceClient = client
+	c.service = service
+	return service, nil
 }
 
-// ExecuteWithChannel execute a given query with chan
-// Channel has ResponseData that can be converted to optional struct array with Convert
-func (q *Query) ExecuteWithChannel(resChan chan ResponseData) {
-	if q.JobConfig != nil {
-		go q.retrieveRowsWithJobConfig(resChan)
-	} else {
-		go q.retrieveRows(resChan)
-	}
+// Service returns the authenticated service of bigquery API, building it if needed
+// It lets callers use features not covered by the client without authenticating again
+// The service is shared with the client, so changing it is at the caller's own risk
+func (c *Client) Service() (*bigquery.Service, error) {
+	return c.getService()
 }
 
-func (q *Query) retrieveRows(receiver chan ResponseData) ([]*bigquery.TableFieldSchema, []*bigquery.TableRow, error) {
-	service, err := q.Client.getService()
-	if err != nil {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Err: err,
-			}
-		}
-		return nil, nil, err
+// Close closes idle connections of the http client used by the service and discards the service
+// The client is still usable after Close since the service is rebuilt on the next call
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.serviceClient != nil {
+		closeIdleConnections(c.serviceClient.Transport)
 	}
+	c.serviceClient = nil
+	c.service = nil
+	return nil
+}
 
-	query := &bigquery.QueryRequest{
-		DefaultDataset: q.Client.datasetRef,
-		MaxResults:     q.size,
-		Kind:           "json",
-		Query:          q.QueryString,
+// closeIdleConnections closes idle connections of a given transport or its base transport of oauth2
+func closeIdleConnections(rt http.RoundTripper) {
+	if t, ok := rt.(*oauth2.Transport); ok {
+		rt = t.Base
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
 	}
+	if closer, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
 
-	qr, err := service.Jobs.Query(query.DefaultDataset.ProjectId, query).Do()
-	if err != nil {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Err: err,
-			}
+// authenticatedClient builds a http client authenticated by the default credentials or the jwt config with a given context
+func (c *Client) authenticatedClient(ctx context.Context) (*http.Client, error) {
+	if c.httpClient != nil {
+		if _, ok := c.httpClient.Transport.(*oauth2.Transport); ok || (c.jwtConfig == nil && c.tokenSource == nil) {
+			return c.httpClient, nil
 		}
-		return nil, nil, err
 	}
 
-	if qr.JobComplete && qr.TotalRows <= uint64(q.size) {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Fields: qr.Schema.Fields,
-				Rows:   qr.Rows,
-			}
-			close(receiver)
+	if c.tokenSource != nil {
+		client := &http.Client{}
+		if c.httpClient != nil {
+			*client = *c.httpClient
 		}
-
-		return qr.Schema.Fields, qr.Rows, nil
+		client.Transport = &oauth2.Transport{Source: c.tokenSource, Base: client.Transport}
+		return client, nil
 	}
 
-	var rowCount int
-	rows := make([]*bigquery.TableRow, 0, int(qr.TotalRows))
-	if qr.JobComplete {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Rows:   qr.Rows,
-				Fields: qr.Schema.Fields,
-			}
-		} else {
-			rows = append(rows, qr.Rows...)
-		}
-		rowCount = len(qr.Rows)
+	if c.jwtConfig == nil {
+		return nil, errors.New("Not initialized")
+	}
+	if len(c.jwtConfig.Scopes) == 0 {
+		return nil, errors.New("No scopes")
 	}
 
-	jobRef := qr.JobReference
-	pageToken := qr.PageToken
-	for {
-		qrc := service.Jobs.GetQueryResults(jobRef.ProjectId, jobRef.JobId)
-		if len(pageToken) != 0 {
-			qrc.PageToken(pageToken)
-		}
-		qrr, err := qrc.Do()
-		if err != nil {
-			if receiver != nil {
-				receiver <- ResponseData{
-					Err: err,
-				}
-			}
-			return nil, nil, err
-		}
+	if c.httpClient == nil {
+		return c.jwtConfig.Client(ctx), nil
+	}
 
-		res := ResponseData{}
+	// settings of the http client like Timeout are kept, and tokens are also fetched through it
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
+	client := *c.httpClient
+	client.Transport = &oauth2.Transport{Source: c.jwtConfig.TokenSource(ctx), Base: c.httpClient.Transport}
+	return &client, nil
+}
 
-		if qrr.Schema != nil {
-			res.Fields = qrr.Schema.Fields
-		}
+// WithHTTPClient sets a http client used to send requests to bigquery
+// The client is wrapped with oauth2 transport by the default credentials or the jwt config
+// unless its transport is already *oauth2.Transport
+// This lets callers set timeouts, proxies or transports for testing
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.httpClient = hc
+	c.serviceClient = nil
+	c.service = nil
+	return c
+}
 
-		if qrr.JobComplete {
-			rowCount += len(qrr.Rows)
-			if receiver != nil {
-				res.Rows = qrr.Rows
-				receiver <- res
-			} else {
-				rows = append(rows, qrr.Rows...)
-			}
-		}
+// WithEndpoint sets an endpoint of bigquery API like https://bigquery.p.googleapis.com/bigquery/v2/
+// This lets the client send requests through Private Google Access or VPC Service Controls
+func (c *Client) WithEndpoint(endpoint string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		if qrr.JobComplete && uint64(rowCount) >= qrr.TotalRows {
-			if receiver != nil {
-				close(receiver)
-			}
-			return res.Fields, rows, nil
-		}
+	c.endpoint = endpoint
+	c.serviceClient = nil
+	c.service = nil
+	return c
+}
 
-		if qrr.JobReference != nil {
-			jobRef = qrr.JobReference
-			pageToken = qrr.PageToken
+// Clone returns a copy of the client which builds its own service on the first call
+// Settings like the dataset, retry policy and converters are copied from the client
+func (c *Client) Clone() *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clone := &Client{
+		location:     c.location,
+		endpoint:     c.endpoint,
+		retry:        c.retry,
+		pollInterval: c.pollInterval,
+		httpClient:   c.httpClient,
+		tokenSource:  c.tokenSource,
+		converters:   append([]Converter(nil), c.converters...),
+		matcher:      c.matcher,
+		queryService: c.queryService,
+		logger:       c.logger,
+
+		billingProject:  c.billingProject,
+		insertBatchSize: c.insertBatchSize,
+	}
+	if c.insertSchemas != nil {
+		clone.insertSchemas = make(map[string][]*bigquery.TableFieldSchema, len(c.insertSchemas))
+		for tableID, fields := range c.insertSchemas {
+			clone.insertSchemas[tableID] = fields
 		}
 	}
+	if c.jwtConfig != nil {
+		jwtConfig := *c.jwtConfig
+		jwtConfig.Scopes = append([]string(nil), c.jwtConfig.Scopes...)
+		clone.jwtConfig = &jwtConfig
+	}
+	if c.datasetRef != nil {
+		datasetRef := *c.datasetRef
+		clone.datasetRef = &datasetRef
+	}
+	return clone
 }
 
-func (q *Query) retrieveRowsWithJobConfig(receiver chan ResponseData) ([]*bigquery.TableFieldSchema, []*bigquery.TableRow, error) {
-	service, err := q.Client.getService()
-	if err != nil {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Err: err,
-			}
-		}
-		return nil, nil, err
+// WithSubject returns a copy of the client impersonating a given user by domain-wide delegation
+// The client itself is unchanged, and the subject only applies to a jwt client
+func (c *Client) WithSubject(subject string) *Client {
+	clone := c.Clone()
+	if clone.jwtConfig != nil {
+		clone.jwtConfig.Subject = subject
 	}
+	return clone
+}
 
-	jobConfigQuery := bigquery.JobConfigurationQuery{
-		Query: q.QueryString,
+// WithTokenURL overrides the oauth2 token URL used by a jwt client
+// The default is https://accounts.google.com/o/oauth2/token, and the service is rebuilt with the URL on the next call
+func (c *Client) WithTokenURL(url string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.jwtConfig != nil {
+		c.jwtConfig.TokenURL = url
 	}
-	if q.JobConfig != nil {
-		jobConfigQuery.AllowLargeResults = q.JobConfig.AllowLargeResults
-		jobConfigQuery.WriteDisposition = string(q.JobConfig.WriteDisposition)
-		jobConfigQuery.CreateDisposition = string(q.JobConfig.CreateDisposition)
-		jobConfigQuery.DestinationTable = &bigquery.TableReference{DatasetId: q.Client.datasetRef.DatasetId, ProjectId: q.Client.datasetRef.ProjectId, TableId: q.JobConfig.TempTableName}
+	c.serviceClient = nil
+	c.service = nil
+	return c
+}
+
+// WithScopes overrides the oauth2 scopes used by a jwt client
+// The default is bigquery.BigqueryScope, and the service is rebuilt with the scopes on the next call
+// At least one scope is required, and building the service fails without any
+func (c *Client) WithScopes(scopes ...string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.jwtConfig != nil {
+		c.jwtConfig.Scopes = append([]string(nil), scopes...)
 	}
+	c.serviceClient = nil
+	c.service = nil
+	return c
+}
 
-	job := bigquery.Job{
-		Configuration: &bigquery.JobConfiguration{
-			Query: &jobConfigQuery,
-		},
+// Dataset sets a target dataset reference
+func (c *Client) Dataset(projectID string, datasetID string) *Client {
+	c.datasetRef = &bigquery.DatasetReference{
+		DatasetId: datasetID,
+		ProjectId: projectID,
 	}
+	return c
+}
 
-	insertedJob, err := service.Jobs.Insert(q.Client.datasetRef.ProjectId, &job).Do()
-	if err != nil {
-		return nil, nil, err
+// SetBillingProject sets a project to run and bill jobs in separately from the project of the dataset
+// The dataset is still used for unqualified tables, so a query can read the dataset in another project
+func (c *Client) SetBillingProject(projectID string) *Client {
+	c.billingProject = projectID
+	return c
+}
+
+// jobProject returns an ID of the project to run jobs in
+func (c *Client) jobProject() string {
+	if c.billingProject != "" {
+		return c.billingProject
+	}
+	if c.datasetRef != nil {
+		return c.datasetRef.ProjectId
 	}
+	return ""
+}
 
-	qr, err := service.Jobs.GetQueryResults(q.Client.datasetRef.ProjectId, insertedJob.JobReference.JobId).Do()
-	if err != nil {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Err: err,
-			}
-		}
-		return nil, nil, err
+// SetLocation sets a location of jobs such as EU or asia-northeast1
+// It is required for datasets outside the US and EU multi-regions
+func (c *Client) SetLocation(location string) *Client {
+	c.location = location
+	return c
+}
+
+// SetInsertBatchSize sets the max number of rows sent in a streaming insert request
+// Rows are split into requests of the size since bigquery limits the size of a request,
+// and the default size is used if it is not positive
+func (c *Client) SetInsertBatchSize(size int) *Client {
+	c.insertBatchSize = size
+	return c
+}
+
+// Query issues a new query instance
+func (c *Client) Query(queryString string) *Query {
+	return &Query{
+		Client:      c,
+		QueryString: queryString,
+		size:        defaultPageSize,
 	}
+}
 
-	if qr.JobComplete && qr.TotalRows <= uint64(len(qr.Rows)) {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Fields: qr.Schema.Fields,
-				Rows:   qr.Rows,
-			}
-			close(receiver)
-		}
+// QueryInProject issues a new query billed to a given project without a default dataset
+// Tables in the query must be qualified like project.dataset.table
+func (c *Client) QueryInProject(projectID string, queryString string) *Query {
+	q := c.Query(queryString)
+	q.projectID = projectID
+	return q
+}
 
-		return qr.Schema.Fields, qr.Rows, nil
+// SetPageSize sets the max number of rows fetched in a page
+func (q *Query) SetPageSize(n int64) *Query {
+	if n <= 0 {
+		q.err = errors.New("Invalid page size")
+		return q
 	}
+	q.size = n
+	return q
+}
 
-	var rowCount int
-	rows := make([]*bigquery.TableRow, 0, int(qr.TotalRows))
-	if qr.JobComplete {
-		if receiver != nil {
-			receiver <- ResponseData{
-				Rows:   qr.Rows,
-				Fields: qr.Schema.Fields,
-			}
-		} else {
-			rows = append(rows, qr.Rows...)
-		}
-		rowCount = len(qr.Rows)
+// Limit sets the max number of rows fetched for the query
+// Pages are no longer fetched once the limit is reached, unlike LIMIT clause in the query
+func (q *Query) Limit(n int) *Query {
+	if n <= 0 {
+		q.err = errors.New("Invalid limit")
+		return q
 	}
+	q.limit = n
+	return q
+}
 
-	jobRef := insertedJob.JobReference
-	pageToken := qr.PageToken
-	for {
-		qrc := service.Jobs.GetQueryResults(jobRef.ProjectId, jobRef.JobId)
-		if len(pageToken) != 0 {
-			qrc.PageToken(pageToken)
-		}
-		qrr, err := qrc.Do()
-		if err != nil {
-			if receiver != nil {
-				receiver <- ResponseData{
-					Err: err,
-				}
-			}
-			return nil, nil, err
-		}
+// OnProgress sets a function called after each page of results is fetched
+// It receives the number of rows fetched so far and the total number of rows of the query
+func (q *Query) OnProgress(fn func(fetched, total uint64)) *Query {
+	q.onProgress = fn
+	return q
+}
 
-		res := ResponseData{}
+// SetTimeout limits time to wait for completion of the query
+// The query waits for completion without limit by default, and ErrQueryTimeout is returned after the limit
+func (q *Query) SetTimeout(d time.Duration) *Query {
+	if d <= 0 {
+		q.err = errors.New("Invalid timeout")
+		return q
+	}
+	q.timeout = d
+	return q
+}
 
-		if qrr.Schema != nil {
-			res.Fields = qrr.Schema.Fields
-		}
+// SetQueryTimeout sets milliseconds to wait for completion of the query in the first request
+// The query is polled after the wait if it is still running, while SetTimeout limits time for the whole query
+// The wait defaults to the timeout set by SetTimeout, or 10 seconds by bigquery without it
+func (q *Query) SetQueryTimeout(ms int64) *Query {
+	if ms <= 0 {
+		q.err = errors.New("Invalid query timeout")
+		return q
+	}
+	q.queryTimeoutMs = ms
+	return q
+}
 
-		if qrr.JobComplete {
-			rowCount += len(qrr.Rows)
-			if receiver != nil {
-				res.Rows = qrr.Rows
-				res.Fields = qrr.Schema.Fields
-				receiver <- res
-			} else {
-				rows = append(rows, qrr.Rows...)
-			}
-		}
+// SetPriority sets query priority
+// PriorityBatch runs the query as an inserted job even without job configuration
+func (q *Query) SetPriority(p Priority) *Query {
+	q.priority = p
+	return q
+}
 
-		if qrr.JobComplete && uint64(rowCount) >= qrr.TotalRows {
-			if receiver != nil {
-				close(receiver)
-			}
-			return res.Fields, rows, nil
+// SetLabels sets labels attached to the query job
+// Keys and values must consist of lowercase letters, digits, underscores and dashes up to 63 characters
+// and keys must start with a lowercase letter
+// Labels are only attached to an inserted job, so the query runs as a job even without job configuration
+func (q *Query) SetLabels(labels map[string]string) *Query {
+	if len(labels) > maxLabels {
+		q.err = fmt.Errorf("Too many labels: %d", len(labels))
+		return q
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			q.err = fmt.Errorf("Invalid label key: %q", key)
+			return q
 		}
-
-		if qrr.JobReference != nil {
-			jobRef = qrr.JobReference
-			pageToken = qrr.PageToken
+		if !labelValuePattern.MatchString(value) {
+			q.err = fmt.Errorf("Invalid label value for %q: %q", key, value)
+			return q
 		}
 	}
+	q.labels = labels
+	return q
 }
 
-// Convert converts bigquery data to a given slice of a struct
-// Compare bq type with struct property type
-// ex..
-// STRING -> string
-// INTEGER -> int, int8, int16, int32, int64
-// FLOAT -> float32, float64
-// TIMESTAMP -> int64 //timestamp string is converted to unixtime milli seconds
-// BOOLEAN -> bool
-// TODO RECORD -> not supported yet
-func Convert(fields []*bigquery.TableFieldSchema, rows []*bigquery.TableRow, result interface{}) error {
-	resultV := reflect.ValueOf(result)
-	if resultV.Kind() != reflect.Ptr || resultV.Elem().Kind() != reflect.Slice {
-		return errors.New("Not pointer")
+// SetJobIDPrefix sets a prefix of the job ID to correlate the query job with logs of the caller
+// The job ID is the prefix followed by a random suffix, and the prefix must consist of letters, digits, underscores and dashes
+// The prefix is only applied to an inserted job, so the query runs as a job even without job configuration
+func (q *Query) SetJobIDPrefix(prefix string) *Query {
+	if prefix == "" || !jobIDPattern.MatchString(prefix) {
+		q.err = fmt.Errorf("Invalid job ID prefix: %q", prefix)
+		return q
 	}
+	q.jobIDPrefix = prefix
+	return q
+}
 
-	sliceV := resultV.Elem()
-	sliceV = sliceV.Slice(0, sliceV.Cap())
-	elemT := sliceV.Type().Elem()
+// UseQueryCache sets whether to look up query results from the cache
+// BigQuery uses the cache by default
+func (q *Query) UseQueryCache(useCache bool) *Query {
+	q.useCache = googleapi.Bool(useCache)
+	return q
+}
 
-	var count int
-	for i := 0; i < len(rows); i++ {
-		if elemT.NumField() != len(rows[i].F) {
-			return errors.New("Invalid result element")
-		}
-		elemP := reflect.New(elemT)
+// UseLegacySQL sets whether to run the query in legacy SQL or standard SQL
+// It overrides UseLegacySQL of job configuration, and bigquery runs a query in legacy SQL if neither is set
+func (q *Query) UseLegacySQL(legacy bool) *Query {
+	q.useLegacy = googleapi.Bool(legacy)
+	return q
+}
+
+// SetMaxBytesBilled limits bytes billed for the query
+// BigQuery fails the query without charge if it would exceed the limit
+// The limit is only applied to an inserted job, so the query runs as a job even without job configuration
+func (q *Query) SetMaxBytesBilled(n int64) *Query {
+	q.maxBilled = n
+	return q
+}
+
+// FlattenResults sets whether to flatten nested and repeated fields in results of a legacy SQL query
+// BigQuery flattens results by default, and not flattening requires AllowLargeResults and TempTableName of job configuration
+// The option is only applied to an inserted job, so the query runs as a job even without job configuration
+func (q *Query) FlattenResults(flatten bool) *Query {
+	q.flatten = googleapi.Bool(flatten)
+	return q
+}
+
+// LenientTypes allows converting results into fields of different but coercible kinds
+// STRING cells are parsed into integer and float fields, and INTEGER and FLOAT cells are set into string fields
+// Types are strictly checked by default
+func (q *Query) LenientTypes() *Query {
+	q.lenient = true
+	return q
+}
+
+// IgnoreUnknownColumns skips columns without a field of the same name when converting results
+// Columns are mapped into fields by names case-insensitively, or by the matcher of the client if set
+func (q *Query) IgnoreUnknownColumns() *Query {
+	q.ignoreUnknown = true
+	return q
+}
+
+// ExecuteToTable executes a given query writing results into a given table in the dataset and waits for its completion
+// Results are written as large results without being fetched, and a JobError is returned if the job fails
+func (q *Query) ExecuteToTable(destTableID string, writeDisp WriteDisp, createDisp CreateDisp) error {
+	service, err := q.getQueryService()
+	if err != nil {
+		return err
+	}
+
+	config := q.jobConfiguration()
+	config.Query.DestinationTable = q.destinationTable(destTableID)
+	config.Query.AllowLargeResults = true
+	config.Query.WriteDisposition = string(writeDisp)
+	config.Query.CreateDisposition = string(createDisp)
+
+	insertedJob, err := q.insertJob(context.Background(), service, config)
+	if err != nil {
+		return err
+	}
+	q.jobRef = insertedJob.JobReference
+
+	_, err = q.Client.waitJob(context.Background(), service, insertedJob)
+	return err
+}
+
+// SkipMalformedRows skips rows whose number of cells doesn't match the schema when converting results
+// Indexes of skipped rows are reported by SkippedRows, and conversion fails at such a row by default
+func (q *Query) SkipMalformedRows() *Query {
+	q.skipMalformed = true
+	return q
+}
+
+// SkippedRows returns indexes of rows skipped by SkipMalformedRows in results of the last execution of the query
+// Indexes are counted from the first row of all results, and rows skipped so far are returned while ExecuteStream is running
+func (q *Query) SkippedRows() []int {
+	q.skippedMu.Lock()
+	defer q.skippedMu.Unlock()
+	return append([]int(nil), q.skipped...)
+}
+
+// addSkipped records given indexes of skipped rows, which are counted from a given offset in all results
+func (q *Query) addSkipped(offset int, indexes []int) {
+	if len(indexes) == 0 {
+		return
+	}
+	q.skippedMu.Lock()
+	defer q.skippedMu.Unlock()
+	for _, index := range indexes {
+		q.skipped = append(q.skipped, offset+index)
+	}
+}
+
+// resetSkipped forgets rows skipped by a previous execution of the query
+func (q *Query) resetSkipped() {
+	q.skippedMu.Lock()
+	defer q.skippedMu.Unlock()
+	q.skipped = nil
+}
+
+// SetJobConfig sets job Configuration
+func (q *Query) SetJobConfig(config *JobConfiguration) *Query {
+	q.JobConfig = config
+	return q
+}
+
+// Execute execute a given query
+func (q *Query) Execute(result interface{}) error {
+	return q.ExecuteContext(context.Background(), result)
+}
+
+// ExecuteContext executes a given query with a context used for requests to submit, poll and page the query
+// Polling and paging stop as soon as the context is done, and the error of the context is returned
+// A job still running at that time is canceled on a best-effort basis
+func (q *Query) ExecuteContext(ctx context.Context, result interface{}) error {
+	fields, rows, err := q.retrieveRows(ctx, nil)
+	if err != nil {
+		return err
+	}
+	return q.convert(fields, rows, 0, result)
+}
+
+// ExecuteWithDeadline executes a given query bound to a given context covering submitting, polling and paging
+// It is the same as ExecuteContext, canceling the job if the deadline fires while the query is running
+func (q *Query) ExecuteWithDeadline(ctx context.Context, result interface{}) error {
+	return q.ExecuteContext(ctx, result)
+}
+
+// ExecuteWithChannel execute a given query with chan
+// Channel has ResponseData that can be converted to optional struct array with Convert
+// The channel is closed after all pages are sent or after ResponseData with Err is sent,
+// and sending blocks until the data is received unless the channel is buffered
+func (q *Query) ExecuteWithChannel(resChan chan ResponseData) {
+	go q.retrieveRows(context.Background(), resChan)
+}
+
+// ExecuteStream executes a given query and sends results converted page by page into a given channel
+// out must be a channel of slice of a struct like chan []T, and it is closed after all pages are sent
+// The returned channel receives an error if the query or conversion fails and is closed at completion
+func (q *Query) ExecuteStream(out interface{}) (<-chan error, error) {
+	outV := reflect.ValueOf(out)
+	if outV.Kind() != reflect.Chan || outV.Type().ChanDir()&reflect.SendDir == 0 || outV.Type().Elem().Kind() != reflect.Slice {
+		return nil, errors.New("Not channel of slice")
+	}
+
+	p, err := q.newPager()
+	if err != nil {
+		return nil, err
+	}
 
-		if len(fields) != len(rows[i].F) {
-			return errors.New("Invalid fields")
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(errChan)
+		defer outV.Close()
+
+		// offset is the index of the first row of a page in all results
+		var offset int
+		for {
+			rows, err := p.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			pageV := reflect.New(outV.Type().Elem())
+			if err := q.convert(p.fields, rows, offset, pageV.Interface()); err != nil {
+				errChan <- err
+				return
+			}
+			offset += len(rows)
+			outV.Send(pageV.Elem())
 		}
+	}()
+	return errChan, nil
+}
 
-		for j := 0; j < len(rows[i].F); j++ {
-			elemF := elemP.Elem().Field(j)
-			var isSet bool
-			record, ok := rows[i].F[j].V.(string)
-			if !ok {
-				continue
+// project returns an ID of the project to run the query in
+// A project given by QueryInProject takes precedence over the billing project and the dataset of the client
+func (q *Query) project() string {
+	if q.projectID != "" {
+		return q.projectID
+	}
+	return q.Client.jobProject()
+}
+
+// defaultDataset returns a dataset for unqualified tables in the query
+// No default dataset is used for a query by QueryInProject
+func (q *Query) defaultDataset() *bigquery.DatasetReference {
+	if q.projectID != "" {
+		return nil
+	}
+	return q.Client.datasetRef
+}
+
+// useJobConfig reports whether the query needs to be inserted as a job
+// rather than issued as a synchronous query request
+func (q *Query) useJobConfig() bool {
+	return q.JobConfig != nil || q.priority == PriorityBatch || len(q.labels) > 0 || q.maxBilled > 0 || q.flatten != nil || q.jobIDPrefix != ""
+}
+
+// useLegacySQL returns the SQL dialect of the query set by the query or job configuration
+// A query with parameters runs in standard SQL by default, and nil is returned to leave the default to bigquery otherwise
+func (q *Query) useLegacySQL() *bool {
+	switch {
+	case q.useLegacy != nil:
+		return q.useLegacy
+	case q.JobConfig != nil && q.JobConfig.UseLegacySQL != nil:
+		return q.JobConfig.UseLegacySQL
+	case len(q.params) > 0:
+		return googleapi.Bool(false)
+	}
+	return nil
+}
+
+// validateFlatten checks that unflattened results are written into a destination table as large results
+func validateFlatten(config *bigquery.JobConfigurationQuery) error {
+	if config.FlattenResults == nil || *config.FlattenResults {
+		return nil
+	}
+	if !config.AllowLargeResults || config.DestinationTable == nil || config.DestinationTable.TableId == "" {
+		return errors.New("FlattenResults(false) requires AllowLargeResults and TempTableName of job configuration")
+	}
+	return nil
+}
+
+// convert converts results of the query with converters of the client
+// offset is the index of the first of given rows in all results, which skipped rows are counted from
+func (q *Query) convert(fields []*bigquery.TableFieldSchema, rows []*bigquery.TableRow, offset int, result interface{}) error {
+	opts := q.convertOptions()
+	err := convert(fields, rows, result, opts)
+	q.addSkipped(offset, opts.skipped)
+	return err
+}
+
+// convertOptions returns options to convert results of the query
+func (q *Query) convertOptions() *convertOptions {
+	opts := q.Client.convertOptions()
+	opts.lenient = q.lenient
+	opts.ignoreUnknown = q.ignoreUnknown
+	opts.skipMalformed = q.skipMalformed
+	return opts
+}
+
+// validate returns an error if the query is misconfigured
+func (q *Query) validate() error {
+	if q.err != nil {
+		return q.err
+	}
+	if q.project() == "" {
+		return errors.New("No project")
+	}
+	if len(q.params) > 0 && q.useLegacySQL() != nil && *q.useLegacySQL() {
+		return errors.New("Query parameters are not supported in legacy SQL")
+	}
+	return nil
+}
+
+// getService returns the service of the client unless the query is misconfigured
+func (q *Query) getService() (*bigquery.Service, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	return q.Client.getService()
+}
+
+// getQueryService returns the query service of the client unless the query is misconfigured
+func (q *Query) getQueryService() (QueryService, error) {
+	return q.getQueryServiceContext(context.Background())
+}
+
+// getQueryServiceContext returns the query service of the client with a given context unless the query is misconfigured
+func (q *Query) getQueryServiceContext(ctx context.Context) (QueryService, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	return q.Client.getQueryServiceContext(ctx)
+}
+
+func (q *Query) retrieveRows(ctx context.Context, receiver chan ResponseData) ([]*bigquery.TableFieldSchema, []*bigquery.TableRow, error) {
+	if receiver != nil {
+		// the receiver is closed on errors too so that ranging over it terminates
+		defer close(receiver)
+	}
+
+	p, err := q.newPagerContext(ctx)
+	if err != nil {
+		if receiver != nil {
+			receiver <- ResponseData{
+				Err: err,
 			}
+		}
+		return nil, nil, err
+	}
+	return q.readRows(p, receiver)
+}
 
-			switch fields[j].Type {
-			case fieldTypeString:
-				switch elemF.Kind() {
-				case reflect.String:
-					isSet = true
-					elemF.SetString(record)
-				}
-			case fieldTypeInteger:
-				switch elemF.Kind() {
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64:
-					r, err := strconv.ParseInt(record, 10, 64)
-					if err != nil {
-						return err
-					}
-					isSet = true
-					elemF.SetInt(r)
+// retrieveJobRows fetches all rows of an existing job by the query like retrieveRows
+// The job is polled while it is still running, and a JobError is returned if it failed
+func (q *Query) retrieveJobRows(ctx context.Context, ref *bigquery.JobReference) ([]*bigquery.TableFieldSchema, []*bigquery.TableRow, error) {
+	p, err := q.newPagerContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.resume(ref)
+	return q.readRows(p, nil)
+}
+
+// readRows reads all pages of a given pager, sending them to a given receiver if it is not nil
+func (q *Query) readRows(p *pager, receiver chan ResponseData) ([]*bigquery.TableFieldSchema, []*bigquery.TableRow, error) {
+	var rows []*bigquery.TableRow
+	for {
+		pageRows, err := p.next()
+		if err == io.EOF {
+			return p.fields, rows, nil
+		}
+		if err != nil {
+			if receiver != nil {
+				receiver <- ResponseData{
+					Err: err,
 				}
-			case fieldTypeFloat:
-				switch elemF.Kind() {
-				case reflect.Float32, reflect.Float64:
-					r, err := strconv.ParseFloat(record, 64)
-					if err != nil {
-						return err
-					}
-					isSet = true
-					elemF.SetFloat(r)
+			}
+			return nil, nil, err
+		}
+
+		if receiver != nil {
+			data := ResponseData{
+				Fields: p.fields,
+				Rows:   pageRows,
+			}
+			if p.done {
+				data.TotalRows = p.totalRows
+				data.NumDmlAffectedRows = p.affected
+				data.Stats = p.stats()
+			}
+			receiver <- data
+		} else {
+			if rows == nil {
+				rows = make([]*bigquery.TableRow, 0, p.capacity())
+			}
+			rows = append(rows, pageRows...)
+
+			if q.concurrency > 1 && p.parallelizable() {
+				restRows, err := p.fetchParallel(q.concurrency)
+				if err != nil {
+					return nil, nil, err
 				}
-			//case fieldTypeRecord:
-			// not supported yet
-			case fieldTypeTimestamp:
-				switch elemF.Kind() {
-				case reflect.Int64:
-					r, err := convertExpornent(record)
-					if err != nil {
-						return err
-					}
-					isSet = true
-					elemF.SetInt(r)
+				if restRows != nil {
+					return p.fields, append(rows, restRows...), nil
 				}
-			case fieldTypeBoolean:
-				switch elemF.Kind() {
-				case reflect.Bool:
-					var r bool
-					if record == "true" || record == "1" {
-						r = true
-					}
-					isSet = true
-					elemF.SetBool(r)
+			}
+		}
+	}
+}
+
+// RowCount executes a given query and returns the number of result rows without fetching them
+func (q *Query) RowCount() (uint64, error) {
+	p, err := q.newPager()
+	if err != nil {
+		return 0, err
+	}
+
+	p.size = 0
+	if _, err := p.next(); err != nil {
+		return 0, err
+	}
+	return p.totalRows, nil
+}
+
+// ExecuteDML executes a given DML statement like UPDATE, DELETE or INSERT and returns the number of affected rows
+func (q *Query) ExecuteDML() (int64, error) {
+	p, err := q.newPager()
+	if err != nil {
+		return 0, err
+	}
+
+	p.size = 0
+	if _, err := p.next(); err != nil {
+		return 0, err
+	}
+	return p.affected, nil
+}
+
+// pager fetches results of a query page by page
+type pager struct {
+	q         *Query
+	ctx       context.Context
+	service   QueryService
+	jobRef    *bigquery.JobReference
+	size      int64
+	pageToken string
+	fields    []*bigquery.TableFieldSchema
+	totalRows uint64
+	rowCount  uint64
+	limit     uint64
+	affected  int64
+	deadline  time.Time
+	started   bool
+	completed bool
+	done      bool
+}
+
+// newPager generates a pager for the query
+func (q *Query) newPager() (*pager, error) {
+	return q.newPagerContext(context.Background())
+}
+
+// newPagerContext generates a pager for the query bound to a given context
+func (q *Query) newPagerContext(ctx context.Context) (*pager, error) {
+	service, err := q.getQueryServiceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q.resetSkipped()
+	p := &pager{
+		q:       q,
+		ctx:     ctx,
+		service: service,
+		size:    q.size,
+		limit:   uint64(q.limit),
+	}
+	if q.limit > 0 && int64(q.limit) < p.size {
+		p.size = int64(q.limit)
+	}
+	return p, nil
+}
+
+// next fetches rows of the next page, issuing the query on the first call
+// It polls results until the query completes and returns io.EOF after all pages are fetched
+func (p *pager) next() ([]*bigquery.TableRow, error) {
+	for !p.done {
+		if err := p.ctx.Err(); err != nil {
+			p.cancelJob()
+			return nil, err
+		}
+
+		var err error
+		var res *bigquery.GetQueryResultsResponse
+		polling := p.started
+		if !p.started {
+			res, err = p.start()
+		} else {
+			res, err = p.fetch()
+		}
+		if ctxErr := p.ctx.Err(); ctxErr != nil {
+			p.cancelJob()
+			return nil, ctxErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		if res == nil || !res.JobComplete {
+			if !p.deadline.IsZero() && time.Now().After(p.deadline) {
+				return nil, ErrQueryTimeout
+			}
+			if polling {
+				select {
+				case <-time.After(p.q.Client.pollDelay()):
+				case <-p.ctx.Done():
 				}
 			}
+			continue
+		}
+		if !p.completed {
+			p.completed = true
+			if err := p.q.expireTempTable(); err != nil {
+				return nil, err
+			}
+		}
+
+		if res.Schema != nil {
+			p.fields = res.Schema.Fields
+		}
+		p.totalRows = res.TotalRows
+		p.affected = res.NumDmlAffectedRows
+		rows := res.Rows
+		if p.limit > 0 && p.rowCount+uint64(len(rows)) >= p.limit {
+			// rows beyond the limit are trimmed and no more pages are fetched
+			rows = rows[:p.limit-p.rowCount]
+			p.done = true
+		}
+		p.rowCount += uint64(len(rows))
+		p.pageToken = res.PageToken
+		if p.rowCount >= p.totalRows || len(p.pageToken) == 0 {
+			p.done = true
+		}
+		p.q.Client.debugf("Fetched a page of %d rows (%d/%d)", len(rows), p.rowCount, p.totalRows)
+		p.progress()
+		return rows, nil
+	}
+	return nil, io.EOF
+}
+
+// stats gets statistics of the job of the query, or nil if they are not available
+func (p *pager) stats() *QueryStats {
+	if p.jobRef == nil {
+		return nil
+	}
+
+	var job *bigquery.Job
+	err := p.q.Client.doContext(p.ctx, func() (err error) {
+		job, err = p.service.GetJob(p.q.Client.locatedJob(p.jobRef))
+		return err
+	})
+	if err != nil {
+		return nil
+	}
+	return newQueryStats(job)
+}
+
+// cancelJob cancels the job of the query if it is still running
+// It is best-effort, so an error to cancel is ignored
+func (p *pager) cancelJob() {
+	if p.jobRef == nil || p.completed {
+		return
+	}
+	p.service.CancelJob(p.q.Client.locatedJob(p.jobRef))
+}
+
+// capacity returns a number of rows to preallocate for all results
+// It is capped by the page size since TotalRows can be unreliable or too huge to allocate
+func (p *pager) capacity() int {
+	if p.totalRows < uint64(p.size) {
+		return int(p.totalRows)
+	}
+	return int(p.size)
+}
+
+// progress notifies the number of fetched rows if the query has a progress function
+func (p *pager) progress() {
+	if p.q.onProgress != nil {
+		p.q.onProgress(p.rowCount, p.totalRows)
+	}
+}
+
+// start issues the query and returns the first page if it is already available
+func (p *pager) start() (*bigquery.GetQueryResultsResponse, error) {
+	p.started = true
+	q := p.q
+	if q.timeout > 0 {
+		p.deadline = time.Now().Add(q.timeout)
+	}
+
+	if q.useJobConfig() {
+		insertedJob, err := q.insertJob(p.ctx, p.service, q.jobConfiguration())
+		if err != nil {
+			return nil, err
+		}
+		p.jobRef = insertedJob.JobReference
+		q.jobRef = p.jobRef
+		if err := newJobError(insertedJob.Status); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	query := &bigquery.QueryRequest{
+		DefaultDataset: q.defaultDataset(),
+		MaxResults:     p.size,
+		Kind:           "json",
+		Query:          q.QueryString,
+		UseQueryCache:  q.useCache,
+		UseLegacySql:   q.useLegacySQL(),
+		Location:       q.Client.location,
+		TimeoutMs:      int64(q.timeout / time.Millisecond),
+	}
+	if q.queryTimeoutMs > 0 {
+		query.TimeoutMs = q.queryTimeoutMs
+	}
+	if p.size == 0 {
+		query.ForceSendFields = []string{"MaxResults"}
+	}
+	q.setQueryParameters(query)
 
-			if !isSet {
-				return errors.New("Invalid elememt type")
+	var qr *bigquery.QueryResponse
+	err := q.Client.doContext(p.ctx, func() (err error) {
+		qr, err = p.service.Query(q.project(), query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.jobRef = qr.JobReference
+	q.jobRef = p.jobRef
+	if p.jobRef != nil {
+		q.Client.debugf("Submitted query job %s", p.jobRef.JobId)
+	}
+
+	return &bigquery.GetQueryResultsResponse{
+		JobComplete:        qr.JobComplete,
+		JobReference:       qr.JobReference,
+		NumDmlAffectedRows: qr.NumDmlAffectedRows,
+		PageToken:          qr.PageToken,
+		Rows:               qr.Rows,
+		Schema:             qr.Schema,
+		TotalRows:          qr.TotalRows,
+	}, nil
+}
+
+// resume starts paging results of a given job already inserted instead of issuing the query
+func (p *pager) resume(ref *bigquery.JobReference) {
+	p.started = true
+	p.jobRef = ref
+	p.q.jobRef = ref
+	if p.q.timeout > 0 {
+		p.deadline = time.Now().Add(p.q.timeout)
+	}
+}
+
+// fetch gets the next page of query results
+func (p *pager) fetch() (*bigquery.GetQueryResultsResponse, error) {
+	opts := QueryResultsOptions{
+		PageToken:  p.pageToken,
+		MaxResults: p.size,
+	}
+	if remaining := time.Until(p.deadline); remaining > 0 {
+		// bigquery waits for completion of the query up to the timeout in a request
+		opts.TimeoutMs = int64(remaining / time.Millisecond)
+	}
+
+	var qrr *bigquery.GetQueryResultsResponse
+	err := p.q.Client.doContext(p.ctx, func() (err error) {
+		qrr, err = p.service.GetQueryResults(p.q.Client.locatedJob(p.jobRef), opts)
+		return err
+	})
+	if err != nil {
+		return nil, p.jobError(err)
+	}
+	return qrr, nil
+}
+
+// jobError returns details of the job if it failed, since results of a failed job are reported
+// as a bare request error, or returns a given error otherwise
+func (p *pager) jobError(err error) error {
+	if p.jobRef == nil {
+		return err
+	}
+
+	var job *bigquery.Job
+	getErr := p.q.Client.doContext(p.ctx, func() (err error) {
+		job, err = p.service.GetJob(p.q.Client.locatedJob(p.jobRef))
+		return err
+	})
+	if getErr != nil {
+		return err
+	}
+	if jobErr := newJobError(job.Status); jobErr != nil {
+		return jobErr
+	}
+	return err
+}
+
+// jobConfiguration builds a configuration to insert the query as a job
+func (q *Query) jobConfiguration() *bigquery.JobConfiguration {
+	jobConfigQuery := bigquery.JobConfigurationQuery{
+		DefaultDataset:     q.defaultDataset(),
+		Query:              q.QueryString,
+		Priority:           string(q.priority),
+		UseQueryCache:      q.useCache,
+		MaximumBytesBilled: q.maxBilled,
+		FlattenResults:     q.flatten,
+		UseLegacySql:       q.useLegacySQL(),
+	}
+	if q.JobConfig != nil {
+		jobConfigQuery.AllowLargeResults = q.JobConfig.AllowLargeResults
+		jobConfigQuery.WriteDisposition = string(q.JobConfig.WriteDisposition)
+		jobConfigQuery.CreateDisposition = string(q.JobConfig.CreateDisposition)
+		jobConfigQuery.DestinationTable = q.destinationTable(q.JobConfig.TempTableName)
+		jobConfigQuery.TimePartitioning = q.JobConfig.TimePartitioning.timePartitioning()
+		jobConfigQuery.Clustering = clustering(q.JobConfig.Clustering)
+	}
+	q.setJobParameters(&jobConfigQuery)
+
+	return &bigquery.JobConfiguration{
+		Query:  &jobConfigQuery,
+		Labels: q.labels,
+	}
+}
+
+// tempTable returns a reference to the temp table of job configuration or nil if it is not set
+func (q *Query) tempTable() *bigquery.TableReference {
+	if q.JobConfig == nil || q.JobConfig.TempTableName == "" {
+		return nil
+	}
+	return q.destinationTable(q.JobConfig.TempTableName)
+}
+
+// expireTempTable sets the expiration of the temp table if job configuration has it
+func (q *Query) expireTempTable() error {
+	ref := q.tempTable()
+	if ref == nil || q.JobConfig.TempTableExpiration <= 0 {
+		return nil
+	}
+	service, err := q.Client.getQueryService()
+	if err != nil {
+		return err
+	}
+
+	table := &bigquery.Table{
+		ExpirationTime: time.Now().Add(q.JobConfig.TempTableExpiration).UnixNano() / int64(time.Millisecond),
+	}
+	return q.Client.do(func() error {
+		_, err := service.PatchTable(ref.ProjectId, ref.DatasetId, ref.TableId, table)
+		return err
+	})
+}
+
+// CleanupTempTable deletes the temp table of job configuration after results are read
+// It doesn't return an error if the table is already deleted
+func (q *Query) CleanupTempTable() error {
+	ref := q.tempTable()
+	if ref == nil {
+		return errors.New("No temp table")
+	}
+	service, err := q.Client.getService()
+	if err != nil {
+		return err
+	}
+
+	err = q.Client.do(func() error {
+		return service.Tables.Delete(ref.ProjectId, ref.DatasetId, ref.TableId).Do()
+	})
+	if apiErr, ok := err.(*APIError); ok && apiErr.IsNotFound() {
+		return nil
+	}
+	return err
+}
+
+// destinationTable builds a reference to a given table to write results into
+// The dataset of the client is used unless job configuration overrides it
+func (q *Query) destinationTable(tableID string) *bigquery.TableReference {
+	ref := &bigquery.TableReference{TableId: tableID}
+	if q.JobConfig != nil {
+		ref.ProjectId = q.JobConfig.DestinationProject
+		ref.DatasetId = q.JobConfig.DestinationDataset
+	}
+	if ref.ProjectId == "" {
+		ref.ProjectId = q.project()
+		if q.projectID == "" && q.Client.datasetRef != nil {
+			// the table is in the dataset of the client even if jobs run in the billing project
+			ref.ProjectId = q.Client.datasetRef.ProjectId
+		}
+	}
+	if ref.DatasetId == "" && q.Client.datasetRef != nil {
+		ref.DatasetId = q.Client.datasetRef.DatasetId
+	}
+	return ref
+}
+
+// newJobReference generates a reference for a new job of the query with the job ID prefix if set
+func (q *Query) newJobReference() *bigquery.JobReference {
+	ref := q.Client.newJobReference(q.project())
+	ref.JobId = q.jobIDPrefix + ref.JobId
+	return ref
+}
+
+// insertJob inserts the query as a new job with a given configuration
+func (q *Query) insertJob(ctx context.Context, service QueryService, config *bigquery.JobConfiguration) (*bigquery.Job, error) {
+	if err := validateFlatten(config.Query); err != nil {
+		return nil, err
+	}
+
+	job := bigquery.Job{
+		Configuration: config,
+		JobReference:  q.newJobReference(),
+	}
+
+	insertedJob, err := q.Client.insertJobContext(ctx, service, q.project(), &job)
+	if err != nil {
+		return nil, err
+	}
+	if insertedJob.JobReference != nil {
+		q.Client.debugf("Submitted query job %s", insertedJob.JobReference.JobId)
+	}
+	return insertedJob, nil
+}
+
+// Convert converts bigquery data to a given slice of a struct or pointers to a struct
+// Compare bq type with struct property type
+// ex..
+// STRING -> string
+// INTEGER -> int, int8, int16, int32, int64
+// FLOAT -> float32, float64
+// TIMESTAMP -> int64, time.Time, string //int64 is unix time in microseconds and string is RFC3339 in UTC
+// BOOLEAN -> bool
+// GEOGRAPHY -> string //WKT like POINT(1 2)
+// INTERVAL -> string //canonical format like 1-2 3 4:5:6.789
+// DATE, DATETIME, TIME -> string //canonical format like 2015-02-03, 2015-02-03T04:05:06 and 04:05:06
+// NUMERIC, BIGNUMERIC -> string //decimal like 123.456 without losing precision
+// BYTES -> []byte, string //string is base64 encoded as sent by bigquery
+// JSON -> string, json.RawMessage //or unmarshaled into struct, map, slice and pointer
+// RECORD -> struct, pointer to struct
+// REPEATED -> slice of a type for the column type like []string, []int64 and []struct
+// Converters registered with RegisterConverter are consulted before the built-in conversion
+func Convert(fields []*bigquery.TableFieldSchema, rows []*bigquery.TableRow, result interface{}) error {
+	return convert(fields, rows, result, &convertOptions{})
+}
+
+func convert(fields []*bigquery.TableFieldSchema, rows []*bigquery.TableRow, result interface{}, opts *convertOptions) error {
+	resultV := reflect.ValueOf(result)
+	if resultV.Kind() != reflect.Ptr || resultV.Elem().Kind() != reflect.Slice {
+		return errors.New("Not pointer")
+	}
+
+	sliceV := resultV.Elem()
+	sliceV = sliceV.Slice(0, sliceV.Cap())
+	elemT := sliceV.Type().Elem()
+	// a slice of pointers like []*T gets pointers to newly allocated structs
+	isPtr := elemT.Kind() == reflect.Ptr
+	if isPtr {
+		elemT = elemT.Elem()
+	}
+
+	var indexes []int
+	var count int
+	for i := 0; i < len(rows); i++ {
+		if ok, err := opts.wellFormed(fields, rows[i].F, i); !ok {
+			if err != nil {
+				return err
 			}
+			continue
+		}
+		if indexes == nil {
+			var err error
+			indexes, err = opts.fieldIndexes(fields, elemT)
+			if err != nil {
+				return err
+			}
+		}
+		elemP := reflect.New(elemT)
+		if err := opts.convertRow(fields, rows[i].F, elemP.Elem(), indexes); err != nil {
+			return err
+		}
+		if isPtr {
+			sliceV = reflect.Append(sliceV, elemP)
+		} else {
+			sliceV = reflect.Append(sliceV, elemP.Elem())
 		}
-		sliceV = reflect.Append(sliceV, elemP.Elem())
 		count++
 	}
 	resultV.Elem().Set(sliceV.Slice(0, count))
 	return nil
 }
 
-func convertExpornent(ex string) (int64, error) {
-	eIndex := strings.LastIndex(ex, "E")
-	if eIndex < 0 {
-		return 0, errors.New("Invalid timestamp format")
+// newCellValueError generates an error for a cell whose value is not a string like a RECORD or REPEATED one
+// Such a cell is reported when it cannot be converted into the field instead of being left as a zero value
+func newCellValueError(field *bigquery.TableFieldSchema, index int, value interface{}, f reflect.StructField) error {
+	var valueType string
+	switch value.(type) {
+	case map[string]interface{}:
+		valueType = "record"
+	case []interface{}:
+		valueType = "repeated"
+	default:
+		valueType = fmt.Sprintf("%T", value)
 	}
+	return fmt.Errorf("Column '%s' (%s) at index %d has a %s value which cannot map to field %s (%s)",
+		field.Name, field.Type, index, valueType, f.Name, f.Type.Kind())
+}
 
-	dIndex := strings.LastIndex(ex[:eIndex], ".")
-	if dIndex < 0 {
-		return 0, errors.New("Invalid timestamp format")
+// wellFormed reports whether a row at a given index has a cell for each column
+// A malformed row is recorded as skipped if malformed rows are skipped, or an error is returned
+func (o *convertOptions) wellFormed(fields []*bigquery.TableFieldSchema, cells []*bigquery.TableCell, index int) (bool, error) {
+	if len(fields) == len(cells) {
+		return true, nil
 	}
-	e, err := strconv.Atoi(ex[eIndex+1:])
-	if err != nil {
+	if o.skipMalformed {
+		o.skipped = append(o.skipped, index)
+		return false, nil
+	}
+	return false, fmt.Errorf("Invalid fields at row %d: %d cells for %d columns", index, len(cells), len(fields))
+}
+
+// convertRow converts cells of a row into fields of a given struct value at indexes of columns
+func (o *convertOptions) convertRow(fields []*bigquery.TableFieldSchema, cells []*bigquery.TableCell, elemV reflect.Value, indexes []int) error {
+	for j := 0; j < len(cells); j++ {
+		if indexes[j] < 0 {
+			continue
+		}
+		elemF := elemV.Field(indexes[j])
+		record, ok := cells[j].V.(string)
+		if !ok {
+			if cells[j].V == nil {
+				// NULL leaves the zero value
+				continue
+			}
+			converted, err := o.convertNested(fields[j], cells[j].V, elemF)
+			if err != nil {
+				return err
+			}
+			if !converted {
+				return newCellValueError(fields[j], j, cells[j].V, elemV.Type().Field(indexes[j]))
+			}
+			continue
+		}
+
+		handled, err := o.convertCell(fields[j].Type, record, elemF)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+
+		isSet, err := o.convertValue(fields[j], record, elemF)
+		if err != nil {
+			return err
+		}
+		if !isSet {
+			return &ConvertError{
+				Column: fields[j].Name,
+				Index:  j,
+				Type:   fields[j].Type,
+				Field:  elemV.Type().Field(indexes[j]).Name,
+				Kind:   elemF.Kind(),
+			}
+		}
+	}
+	return nil
+}
+
+// convertValue converts a raw value of a column into a given field and reports whether the field type matches the column
+func (o *convertOptions) convertValue(field *bigquery.TableFieldSchema, record string, elemF reflect.Value) (bool, error) {
+	var isSet bool
+	switch field.Type {
+	case fieldTypeString:
+		switch elemF.Kind() {
+		case reflect.String:
+			isSet = true
+			elemF.SetString(record)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !o.lenient {
+				break
+			}
+			r, err := strconv.ParseInt(record, 10, 64)
+			if err != nil {
+				return false, err
+			}
+			if elemF.OverflowInt(r) {
+				// a value out of the range of the field is reported instead of being wrapped
+				break
+			}
+			isSet = true
+			elemF.SetInt(r)
+		case reflect.Float32, reflect.Float64:
+			if !o.lenient {
+				break
+			}
+			r, err := parseFloat(record)
+			if err != nil {
+				return false, err
+			}
+			isSet = true
+			elemF.SetFloat(r)
+		}
+	case fieldTypeInteger:
+		switch elemF.Kind() {
+		case reflect.String:
+			if !o.lenient {
+				break
+			}
+			isSet = true
+			elemF.SetString(record)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			r, err := strconv.ParseInt(record, 10, 64)
+			if err != nil {
+				return false, err
+			}
+			if elemF.OverflowInt(r) {
+				// a value out of the range of the field is reported instead of being wrapped
+				break
+			}
+			isSet = true
+			elemF.SetInt(r)
+		}
+	case fieldTypeFloat:
+		switch elemF.Kind() {
+		case reflect.String:
+			if !o.lenient {
+				break
+			}
+			isSet = true
+			elemF.SetString(record)
+		case reflect.Float32, reflect.Float64:
+			r, err := parseFloat(record)
+			if err != nil {
+				return false, err
+			}
+			isSet = true
+			elemF.SetFloat(r)
+		}
+	case fieldTypeTimestamp:
+		switch {
+		case elemF.Kind() == reflect.Int64:
+			r, err := parseTimestamp(record)
+			if err != nil {
+				return false, err
+			}
+			isSet = true
+			elemF.SetInt(r)
+		case elemF.Type() == timeType:
+			r, err := parseTimestamp(record)
+			if err != nil {
+				return false, err
+			}
+			isSet = true
+			elemF.Set(reflect.ValueOf(timeFromMicros(r)))
+		case elemF.Kind() == reflect.String:
+			r, err := parseTimestamp(record)
+			if err != nil {
+				return false, err
+			}
+			isSet = true
+			elemF.SetString(timeFromMicros(r).Format(time.RFC3339Nano))
+		}
+	case fieldTypeGeography, fieldTypeInterval, fieldTypeDate, fieldTypeDatetime, fieldTypeTime, fieldTypeNumeric, fieldTypeBigNumeric:
+		switch elemF.Kind() {
+		case reflect.String:
+			isSet = true
+			elemF.SetString(record)
+		}
+	case fieldTypeBytes:
+		switch {
+		case elemF.Kind() == reflect.String:
+			isSet = true
+			elemF.SetString(record)
+		case elemF.Kind() == reflect.Slice && elemF.Type().Elem().Kind() == reflect.Uint8:
+			r, err := base64.StdEncoding.DecodeString(record)
+			if err != nil {
+				return false, fmt.Errorf("Invalid BYTES for %s: %v", field.Name, err)
+			}
+			isSet = true
+			elemF.SetBytes(r)
+		}
+	case fieldTypeJSON:
+		switch elemF.Kind() {
+		case reflect.String:
+			isSet = true
+			elemF.SetString(record)
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Ptr:
+			if elemF.Type() == rawMessageType {
+				isSet = true
+				elemF.SetBytes([]byte(record))
+				break
+			}
+			if err := json.Unmarshal([]byte(record), elemF.Addr().Interface()); err != nil {
+				return false, fmt.Errorf("Invalid JSON for %s: %v", field.Name, err)
+			}
+			isSet = true
+		}
+	case fieldTypeBoolean:
+		switch elemF.Kind() {
+		case reflect.Bool:
+			r, err := parseBool(record)
+			if err != nil {
+				return false, err
+			}
+			isSet = true
+			elemF.SetBool(r)
+		}
+	}
+	return isSet, nil
+}
+
+func parseFloat(f string) (float64, error) {
+	switch f {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(f, 64)
+}
+
+func parseBool(b string) (bool, error) {
+	switch {
+	case strings.EqualFold(b, "true") || b == "1":
+		return true, nil
+	case strings.EqualFold(b, "false") || b == "0":
+		return false, nil
+	}
+	return false, errors.New("Invalid boolean format")
+}
+
+// parseTimestamp parses a timestamp cell in seconds like 1.422943323461123E9 into unix time in microseconds
+// The cell is scaled as a decimal string since float multiplication drifts from exact microseconds
+func parseTimestamp(ts string) (int64, error) {
+	mantissa, exp := ts, 0
+	if eIndex := strings.IndexAny(ts, "eE"); eIndex >= 0 {
+		e, err := strconv.Atoi(ts[eIndex+1:])
+		if err != nil {
+			return 0, errors.New("Invalid timestamp format")
+		}
+		mantissa, exp = ts[:eIndex], e
+	}
+
+	var sign string
+	if strings.HasPrefix(mantissa, "-") {
+		sign, mantissa = "-", mantissa[1:]
+	}
+	intPart, fracPart := mantissa, ""
+	if dIndex := strings.Index(mantissa, "."); dIndex >= 0 {
+		intPart, fracPart = mantissa[:dIndex], mantissa[dIndex+1:]
+	}
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
 		return 0, errors.New("Invalid timestamp format")
 	}
 
-	base, err := strconv.ParseFloat(ex[:eIndex], 64)
-	if err != nil {
-		return 0, err
+	// digits are in units of the last fractional digit, and shifted into microseconds
+	shift := exp + 6 - len(fracPart)
+	switch {
+	case shift > 18:
+		return 0, errors.New("Invalid timestamp format")
+	case shift >= 0:
+		digits += strings.Repeat("0", shift)
+	case -shift >= len(digits):
+		digits = "0"
+	default:
+		digits = digits[:len(digits)+shift]
 	}
-	return int64(base * math.Pow10(e)), nil
+	return strconv.ParseInt(sign+digits, 10, 64)
+}
+
+// timeFromMicros converts unix time in microseconds into time in UTC
+func timeFromMicros(us int64) time.Time {
+	return time.Unix(us/1e6, us%1e6*1e3).UTC()
 }
 
 // InsertRowsByJSON inserts a new row into the desired project, dataset and table or returns an error
 func (c *Client) InsertRowsByJSON(tableID string, rows []map[string]interface{}) error {
-	service, err := c.getService()
+	return c.insertRows(tableID, rows, nil, InsertOptions{})
+}
+
+// insertRows inserts given rows with insert IDs if given
+// Rows are sent in batches, and errors of failed rows in all batches are returned as an InsertError
+// Batches before a failed request are already inserted if the request fails
+func (c *Client) insertRows(tableID string, rows []map[string]interface{}, insertIDs []string, opts InsertOptions) error {
+	ref, err := c.dataset()
 	if err != nil {
 		return err
 	}
+	if err := c.validateRows(tableID, rows, opts); err != nil {
+		return err
+	}
+
+	service, err := c.getQueryService()
+	if err != nil {
+		return err
+	}
+
+	size := c.insertBatchSize
+	if size <= 0 {
+		size = defaultInsertBatchSize
+	}
+
+	var rowErrors []*bigquery.TableDataInsertAllResponseInsertErrors
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		var batchIDs []string
+		if len(insertIDs) > 0 {
+			batchIDs = insertIDs[start:end]
+		}
+
+		batchErrors, err := c.insertBatch(service, ref, tableID, rows[start:end], batchIDs, opts)
+		if err != nil {
+			return err
+		}
+		for _, batchError := range batchErrors {
+			rowError := *batchError
+			rowError.Index += int64(start)
+			rowErrors = append(rowErrors, &rowError)
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		return &InsertError{rowErrors: rowErrors}
+	}
+
+	return nil
+}
+
+// hasInsertIDs reports whether all of given rows have insert IDs
+func hasInsertIDs(rows []*bigquery.TableDataInsertAllRequestRows) bool {
+	for _, row := range rows {
+		if row.InsertId == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// insertBatch inserts given rows in a request and returns errors of failed rows
+func (c *Client) insertBatch(service QueryService, ref *bigquery.DatasetReference, tableID string, rows []map[string]interface{}, insertIDs []string, opts InsertOptions) ([]*bigquery.TableDataInsertAllResponseInsertErrors, error) {
 
 	requestRows := make([]*bigquery.TableDataInsertAllRequestRows, 0, len(rows))
 	for i := range rows {
@@ -514,21 +1836,38 @@ func (c *Client) InsertRowsByJSON(tableID string, rows []map[string]interface{})
 		for key := range rows[i] {
 			data[key] = bigquery.JsonValue(rows[i][key])
 		}
-		requestRows = append(requestRows, &bigquery.TableDataInsertAllRequestRows{
+		requestRow := &bigquery.TableDataInsertAllRequestRows{
 			Json: data,
-		})
+		}
+		if len(insertIDs) > 0 {
+			requestRow.InsertId = insertIDs[i]
+		}
+		requestRows = append(requestRows, requestRow)
 	}
 
-	insertRequest := &bigquery.TableDataInsertAllRequest{Rows: requestRows}
+	insertRequest := &bigquery.TableDataInsertAllRequest{
+		Rows:                requestRows,
+		SkipInvalidRows:     opts.SkipInvalidRows,
+		IgnoreUnknownValues: opts.IgnoreUnknownValues,
+		TemplateSuffix:      opts.TemplateSuffix,
+	}
 
-	result, err := service.Tabledata.InsertAll(c.datasetRef.ProjectId, c.datasetRef.DatasetId, tableID, insertRequest).Do()
-	if err != nil {
+	c.debugf("Inserting %d rows into %s", len(rows), tableID)
+	var result *bigquery.TableDataInsertAllResponse
+	call := func() (err error) {
+		result, err = service.InsertAll(ref.ProjectId, ref.DatasetId, tableID, insertRequest)
 		return err
 	}
-
-	if len(result.InsertErrors) > 0 {
-		return errors.New("Failed to insert")
+	// rows without insert IDs are not deduplicated by bigquery, so they are sent only once
+	var err error
+	if hasInsertIDs(requestRows) {
+		err = c.do(call)
+	} else {
+		err = newAPIError(call())
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return result.InsertErrors, nil
 }
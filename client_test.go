@@ -1,10 +1,28 @@
 package client
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/oauth2"
 	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
 )
 
 type convertRec struct {
@@ -15,6 +33,34 @@ type convertRec struct {
 	IsDeleted bool
 }
 
+type nameRec struct {
+	Name string
+}
+
+// fakeTransport is a http.RoundTripper replying by a given function
+type fakeTransport func(req *http.Request) (*http.Response, error)
+
+func (f fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newFakeClient generates a client whose service sends requests to a given transport
+func newFakeClient(transport http.RoundTripper) *Client {
+	c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+	c.Dataset("winter_test00", "bq_test")
+	c.service, _ = bigquery.New(&http.Client{Transport: transport})
+	return c
+}
+
+// jsonResponse generates a http response with a given status code and json body
+func jsonResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
 func TestNew(t *testing.T) {
 	Convey("Given necessary data for client", t, func() {
 		email := "example@gmail.com"
@@ -33,6 +79,160 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestNewFromJSON(t *testing.T) {
+	Convey("Given a service account JSON key", t, func() {
+		key, err := json.Marshal(map[string]string{
+			"type":           "service_account",
+			"project_id":     "winter_test00",
+			"private_key_id": "test_key_id",
+			"private_key":    string(newTestPrivateKey()),
+			"client_email":   "example@winter_test00.iam.gserviceaccount.com",
+			"token_uri":      "https://oauth2.googleapis.com/token",
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When create a new client from the key", func() {
+			c, err := NewFromJSON(key, "user@example.com")
+
+			Convey("Then client has a config of the key", func() {
+				So(err, ShouldBeNil)
+				So(c.jwtConfig.Email, ShouldEqual, "example@winter_test00.iam.gserviceaccount.com")
+				So(c.jwtConfig.Subject, ShouldEqual, "user@example.com")
+				So(c.jwtConfig.Scopes, ShouldResemble, []string{bigquery.BigqueryScope})
+				So(c.jwtConfig.TokenURL, ShouldEqual, "https://oauth2.googleapis.com/token")
+			})
+		})
+
+		Convey("When create a new client from the key without a subject", func() {
+			c, err := NewFromJSONKey(key)
+
+			Convey("Then client has a config of the key without impersonation", func() {
+				So(err, ShouldBeNil)
+				So(c.jwtConfig.Email, ShouldEqual, "example@winter_test00.iam.gserviceaccount.com")
+				So(c.jwtConfig.Subject, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When create a new client from a malformed key", func() {
+			c, err := NewFromJSON([]byte("this is test pem dummy"), "")
+
+			Convey("Then err is returned", func() {
+				So(c, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestNewWithValidation(t *testing.T) {
+	Convey("Given a valid private key", t, func() {
+		key := newTestPrivateKey()
+
+		Convey("When create a new client with validation", func() {
+			c, err := NewWithValidation("example@gmail.com", key, "")
+
+			Convey("Then client has a config", func() {
+				So(err, ShouldBeNil)
+				So(c.jwtConfig.PrivateKey, ShouldResemble, key)
+			})
+		})
+	})
+
+	Convey("Given a malformed private key", t, func() {
+		key := []byte("this is test pem dummy")
+
+		Convey("When create a new client with validation", func() {
+			c, err := NewWithValidation("example@gmail.com", key, "")
+
+			Convey("Then a key error is returned", func() {
+				So(c, ShouldBeNil)
+				_, ok := err.(*KeyError)
+				So(ok, ShouldBeTrue)
+				So(err.Error(), ShouldEqual, "Invalid private key: not a PEM block")
+			})
+		})
+	})
+
+	Convey("Given a PEM block without a private key", t, func() {
+		key := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("this is test pem dummy")})
+
+		Convey("When create a new client with validation", func() {
+			_, err := NewWithValidation("example@gmail.com", key, "")
+
+			Convey("Then a key error is returned", func() {
+				_, ok := err.(*KeyError)
+				So(ok, ShouldBeTrue)
+				So(err.Error(), ShouldStartWith, "Invalid private key: ")
+			})
+		})
+	})
+}
+
+func TestNewWithDefaultCredentials(t *testing.T) {
+	Convey("Given application default credentials", t, func() {
+		dir, err := ioutil.TempDir("", "bq-client")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		credPath := filepath.Join(dir, "credentials.json")
+		cred := `{"type": "service_account", "client_email": "example@gmail.com", "private_key": "this is test pem dummy"}`
+		So(ioutil.WriteFile(credPath, []byte(cred), 0600), ShouldBeNil)
+
+		original := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credPath)
+		defer os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", original)
+
+		Convey("When create a new client", func() {
+			c, err := NewWithDefaultCredentials(context.Background())
+
+			Convey("Then client has a service", func() {
+				So(err, ShouldBeNil)
+				So(c.jwtConfig, ShouldBeNil)
+
+				service, err := c.getService()
+				So(err, ShouldBeNil)
+				So(service, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When create a new client with an endpoint", func() {
+			c, err := NewWithDefaultCredentials(context.Background())
+			So(err, ShouldBeNil)
+			c.WithEndpoint("https://bigquery.p.googleapis.com/bigquery/v2/")
+
+			Convey("Then the service sends requests to the endpoint", func() {
+				service, err := c.getService()
+				So(err, ShouldBeNil)
+				So(service.BasePath, ShouldEqual, "https://bigquery.p.googleapis.com/bigquery/v2/")
+			})
+		})
+
+		Convey("When create a new client with a http client", func() {
+			c, err := NewWithDefaultCredentials(context.Background())
+			So(err, ShouldBeNil)
+			var called bool
+			c.WithHTTPClient(&http.Client{Transport: fakeTransport(func(req *http.Request) (*http.Response, error) {
+				called = true
+				return jsonResponse(http.StatusOK, `{}`), nil
+			}), Timeout: time.Minute})
+
+			Convey("Then the http client is wrapped with the default credentials", func() {
+				hc, err := c.authenticatedClient(context.Background())
+				So(err, ShouldBeNil)
+				So(hc.Timeout, ShouldEqual, time.Minute)
+				transport, ok := hc.Transport.(*oauth2.Transport)
+				So(ok, ShouldBeTrue)
+				So(transport.Source, ShouldNotBeNil)
+
+				req, _ := http.NewRequest(http.MethodGet, "https://bigquery.googleapis.com/", nil)
+				_, err = transport.Base.RoundTrip(req)
+				So(err, ShouldBeNil)
+				So(called, ShouldBeTrue)
+			})
+		})
+	})
+}
+
 func TestDataset(t *testing.T) {
 	Convey("Given necessary data for client and dataset", t, func() {
 		email := "example@gmail.com"
@@ -74,163 +274,809 @@ func TestGetService(t *testing.T) {
 				So(c.service, ShouldNotBeNil)
 			})
 		})
+
+		Convey("When get a new service with a canceled context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			service, err := c.getServiceContext(ctx)
+
+			Convey("Then the error of the context is returned without building the service", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(service, ShouldBeNil)
+				So(c.service, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a client with a valid key", t, func() {
+		c := New("example@gmail.com", newTestPrivateKey(), "").Dataset("winter_test00", "bq_test")
+
+		Convey("When execute a query with a context carrying a http client", func() {
+			var urls []string
+			hc := &http.Client{Transport: fakeTransport(func(req *http.Request) (*http.Response, error) {
+				urls = append(urls, req.URL.Host+req.URL.Path)
+				if req.URL.Host == "accounts.google.com" {
+					return jsonResponse(http.StatusOK, `{"access_token": "test_token", "token_type": "Bearer", "expires_in": 3600}`), nil
+				}
+				return jsonResponse(http.StatusOK, `{
+					"jobComplete": true,
+					"totalRows": "1",
+					"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+					"rows": [{"f": [{"v": "test_name"}]}]
+				}`), nil
+			})}
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").ExecuteContext(context.WithValue(context.Background(), oauth2.HTTPClient, hc), &res)
+
+			Convey("Then the token is fetched with the context", func() {
+				So(err, ShouldBeNil)
+				So(urls, ShouldHaveLength, 2)
+				So(urls[0], ShouldEqual, "accounts.google.com/o/oauth2/token")
+			})
+		})
 	})
 }
 
-func TestConvert(t *testing.T) {
-	Convey("Given invalid fields", t, func() {
-		fields := []*bigquery.TableFieldSchema{
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "name",
-				Type:        "STRING",
-			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "age",
-				Type:        "INTEGER",
-			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "score",
-				Type:        "FLOAT",
-			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "timestamp",
-				Type:        "TIMESTAMP",
-			},
-		}
+func TestWithTokenURLAndScopes(t *testing.T) {
+	Convey("Given initialized client", t, func() {
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
 
-		rows := []*bigquery.TableRow{
-			&bigquery.TableRow{
-				F: []*bigquery.TableCell{
-					{
-						V: "test_name",
-					},
-					{
-						V: "26",
-					},
-					{
-						V: "12.34",
-					},
-					{
-						V: "1.422943323461E9",
-					},
-				},
-			},
-		}
+		Convey("When override token url and scopes", func() {
+			c.WithTokenURL("https://example.com/token").WithScopes(bigquery.CloudPlatformReadOnlyScope)
 
-		res := []convertRec{}
+			Convey("Then jwt config is overridden", func() {
+				So(c.jwtConfig.TokenURL, ShouldEqual, "https://example.com/token")
+				So(c.jwtConfig.Scopes, ShouldResemble, []string{bigquery.CloudPlatformReadOnlyScope})
 
-		Convey("When convert bigquery data", func() {
-			err := Convert(fields, rows, &res)
+				service, err := c.getService()
+				So(err, ShouldBeNil)
+				So(service, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When remove all scopes", func() {
+			c.WithScopes()
 
 			Convey("Then err is returned", func() {
+				service, err := c.getService()
+				So(service, ShouldBeNil)
 				So(err, ShouldNotBeNil)
-				So(err.Error(), ShouldEqual, "Invalid result element")
+				So(err.Error(), ShouldEqual, "No scopes")
+			})
+
+			Convey("Then the service is built after scopes are set again", func() {
+				c.WithScopes(bigquery.CloudPlatformReadOnlyScope)
+
+				service, err := c.getService()
+				So(err, ShouldBeNil)
+				So(service, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When override scopes after the service is built", func() {
+			built, err := c.getService()
+			So(err, ShouldBeNil)
+			c.WithScopes(bigquery.CloudPlatformReadOnlyScope)
+
+			Convey("Then the service is rebuilt with the scopes", func() {
+				So(c.service, ShouldBeNil)
+
+				service, err := c.getService()
+				So(err, ShouldBeNil)
+				So(service, ShouldNotPointTo, built)
+			})
+		})
 
+		Convey("When override token url after the service is built", func() {
+			built, err := c.getService()
+			So(err, ShouldBeNil)
+			c.WithTokenURL("https://example.com/token")
+
+			Convey("Then the service is rebuilt with the url", func() {
+				So(c.service, ShouldBeNil)
+
+				service, err := c.getService()
+				So(err, ShouldBeNil)
+				So(service, ShouldNotPointTo, built)
 			})
 		})
 	})
+}
 
-	Convey("Given bigquery fields, rows and result receiver containing nil", t, func() {
-		fields := []*bigquery.TableFieldSchema{
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "name",
-				Type:        "STRING",
-			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "age",
-				Type:        "INTEGER",
-			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "score",
-				Type:        "FLOAT",
-			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "timestamp",
-				Type:        "TIMESTAMP",
-			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "idDeleted",
-				Type:        "BOOLEAN",
-			},
-		}
+// newTestPrivateKey generates a PEM encoded RSA key for signing jwt in tests
+func newTestPrivateKey() []byte {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
 
-		rows := []*bigquery.TableRow{
-			&bigquery.TableRow{
-				F: []*bigquery.TableCell{
-					{
-						V: nil,
-					},
-					{
-						V: nil,
-					},
-					{
-						V: nil,
-					},
-					{
-						V: nil,
-					},
-					{
-						V: nil,
-					},
-				},
-			},
-		}
+func TestWithHTTPClient(t *testing.T) {
+	Convey("Given a client with a http client recording requests", t, func() {
+		var urls, authorizations []string
+		hc := &http.Client{Timeout: 30 * time.Second, Transport: fakeTransport(func(req *http.Request) (*http.Response, error) {
+			urls = append(urls, req.URL.Host+req.URL.Path)
+			authorizations = append(authorizations, req.Header.Get("Authorization"))
+			if req.URL.Host == "accounts.google.com" {
+				return jsonResponse(http.StatusOK, `{"access_token": "test_token", "token_type": "Bearer", "expires_in": 3600}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test.test_table"}`), nil
+		})}
+		c := New("example@gmail.com", newTestPrivateKey(), "").Dataset("winter_test00", "bq_test").WithHTTPClient(hc)
 
-		res := []convertRec{}
+		Convey("When get the authenticated client", func() {
+			client, err := c.authenticatedClient(context.Background())
 
-		Convey("When convert bigquery data", func() {
-			err := Convert(fields, rows, &res)
+			Convey("Then settings of the http client are kept", func() {
+				So(err, ShouldBeNil)
+				So(client, ShouldNotPointTo, hc)
+				So(client.Timeout, ShouldEqual, 30*time.Second)
+				_, ok := client.Transport.(*oauth2.Transport)
+				So(ok, ShouldBeTrue)
+			})
+		})
 
-			Convey("Then results resolved nil are set into a given receiver", func() {
+		Convey("When send a request to bigquery", func() {
+			exists, err := c.TableExists("test_table")
+
+			Convey("Then requests are sent through the http client with oauth2", func() {
 				So(err, ShouldBeNil)
-				So(len(res), ShouldEqual, 1)
-				So(res[0].Name, ShouldEqual, "")
-				So(res[0].Age, ShouldEqual, 0)
-				So(res[0].Score, ShouldEqual, 0.0)
-				So(res[0].Timestamp, ShouldEqual, 0)
-				So(res[0].IsDeleted, ShouldEqual, false)
+				So(exists, ShouldBeTrue)
+				So(urls, ShouldResemble, []string{
+					"accounts.google.com/o/oauth2/token",
+					"bigquery.googleapis.com/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table",
+				})
+				So(authorizations[1], ShouldEqual, "Bearer test_token")
+			})
+		})
+	})
+
+	Convey("Given a client with an authenticated http client", t, func() {
+		var urls []string
+		hc := &http.Client{Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test_token"}),
+			Base: fakeTransport(func(req *http.Request) (*http.Response, error) {
+				urls = append(urls, req.URL.Host+req.URL.Path)
+				return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test.test_table"}`), nil
+			}),
+		}}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "").Dataset("winter_test00", "bq_test").WithHTTPClient(hc)
+
+		Convey("When send a request to bigquery", func() {
+			_, err := c.TableExists("test_table")
 
+			Convey("Then the http client is used directly", func() {
+				So(err, ShouldBeNil)
+				So(urls, ShouldResemble, []string{
+					"bigquery.googleapis.com/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table",
+				})
 			})
 		})
 	})
+}
 
-	Convey("Given bigquery fields, rows and result receiver", t, func() {
-		fields := []*bigquery.TableFieldSchema{
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "name",
-				Type:        "STRING",
-			},
-			{
+func TestWithEndpoint(t *testing.T) {
+	Convey("Given a client with an endpoint", t, func() {
+		var urls []string
+		hc := &http.Client{Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test_token"}),
+			Base: fakeTransport(func(req *http.Request) (*http.Response, error) {
+				urls = append(urls, req.URL.Host+req.URL.Path)
+				return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test.test_table"}`), nil
+			}),
+		}}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "").Dataset("winter_test00", "bq_test").
+			WithHTTPClient(hc).
+			WithEndpoint("https://bigquery.p.googleapis.com/bigquery/v2/")
+
+		Convey("When get the service", func() {
+			service, err := c.getService()
+
+			Convey("Then the service has the endpoint as its base path", func() {
+				So(err, ShouldBeNil)
+				So(service.BasePath, ShouldEqual, "https://bigquery.p.googleapis.com/bigquery/v2/")
+			})
+		})
+
+		Convey("When send a request to bigquery", func() {
+			_, err := c.TableExists("test_table")
+
+			Convey("Then the request is sent to the endpoint", func() {
+				So(err, ShouldBeNil)
+				So(urls, ShouldResemble, []string{
+					"bigquery.p.googleapis.com/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table",
+				})
+			})
+		})
+	})
+}
+
+func TestService(t *testing.T) {
+	Convey("Given a client with an authenticated http client", t, func() {
+		var urls []string
+		hc := &http.Client{Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test_token"}),
+			Base: fakeTransport(func(req *http.Request) (*http.Response, error) {
+				urls = append(urls, req.URL.Host+req.URL.Path)
+				return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test"}`), nil
+			}),
+		}}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "").Dataset("winter_test00", "bq_test").WithHTTPClient(hc)
+
+		Convey("When get the service", func() {
+			service, err := c.Service()
+
+			Convey("Then the service of the client is returned", func() {
+				So(err, ShouldBeNil)
+				So(service, ShouldNotBeNil)
+
+				again, _ := c.Service()
+				So(again, ShouldEqual, service)
+
+				_, err := service.Datasets.Get("winter_test00", "bq_test").Do()
+				So(err, ShouldBeNil)
+				So(urls, ShouldResemble, []string{"bigquery.googleapis.com/bigquery/v2/projects/winter_test00/datasets/bq_test"})
+			})
+		})
+	})
+}
+
+// closingTransport is a fakeTransport counting calls of CloseIdleConnections
+type closingTransport struct {
+	fakeTransport
+	closed int
+}
+
+func (t *closingTransport) CloseIdleConnections() {
+	t.closed++
+}
+
+func TestClose(t *testing.T) {
+	Convey("Given a client with an authenticated http client", t, func() {
+		var count int
+		transport := &closingTransport{fakeTransport: func(req *http.Request) (*http.Response, error) {
+			count++
+			return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test.test_table"}`), nil
+		}}
+		hc := &http.Client{Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test_token"}),
+			Base:   transport,
+		}}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "").Dataset("winter_test00", "bq_test").WithHTTPClient(hc)
+		_, err := c.TableExists("test_table")
+		So(err, ShouldBeNil)
+
+		Convey("When close the client", func() {
+			err := c.Close()
+
+			Convey("Then idle connections are closed and the service is discarded", func() {
+				So(err, ShouldBeNil)
+				So(transport.closed, ShouldEqual, 1)
+				So(c.service, ShouldBeNil)
+			})
+
+			Convey("Then the client is still usable", func() {
+				exists, err := c.TableExists("test_table")
+				So(err, ShouldBeNil)
+				So(exists, ShouldBeTrue)
+				So(count, ShouldEqual, 2)
+				So(c.service, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestWithSubject(t *testing.T) {
+	Convey("Given a client with a cached service", t, func() {
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "admin@example.com").Dataset("winter_test00", "bq_test")
+		service, err := c.getService()
+		So(err, ShouldBeNil)
+
+		Convey("When clone the client with another subject", func() {
+			clone := c.WithSubject("user@example.com")
+
+			Convey("Then the clone impersonates the subject with its own service", func() {
+				So(clone.jwtConfig.Subject, ShouldEqual, "user@example.com")
+				So(clone.jwtConfig.Email, ShouldEqual, "example@gmail.com")
+				So(clone.datasetRef, ShouldResemble, c.datasetRef)
+				So(clone.service, ShouldBeNil)
+
+				cloneService, err := clone.getService()
+				So(err, ShouldBeNil)
+				So(cloneService, ShouldNotPointTo, service)
+			})
+
+			Convey("Then the original client is unchanged", func() {
+				clone.Dataset("winter_test01", "bq_other")
+				clone.WithScopes("https://www.googleapis.com/auth/bigquery.readonly")
+
+				So(c.jwtConfig.Subject, ShouldEqual, "admin@example.com")
+				So(c.jwtConfig.Scopes, ShouldResemble, []string{bigquery.BigqueryScope})
+				So(c.datasetRef.ProjectId, ShouldEqual, "winter_test00")
+				So(c.service, ShouldPointTo, service)
+			})
+		})
+	})
+}
+
+func TestGetServiceConcurrently(t *testing.T) {
+	Convey("Given initialized client", t, func() {
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+		c.Dataset("winter_test00", "bq_test")
+
+		Convey("When get a service from multiple goroutines", func() {
+			services := make([]*bigquery.Service, 10)
+			var wg sync.WaitGroup
+			for i := range services {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					services[i], _ = c.getService()
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then the same service is reused", func() {
+				for i := range services {
+					So(services[i], ShouldNotBeNil)
+					So(services[i], ShouldEqual, services[0])
+				}
+			})
+		})
+	})
+}
+
+func TestSetPageSize(t *testing.T) {
+	Convey("Given a client whose service completes a query on GetQueryResults", t, func() {
+		var queryRequest bigquery.QueryRequest
+		var maxResults string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				json.NewDecoder(req.Body).Decode(&queryRequest)
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": false
+				}`), nil
+			}
+			maxResults = req.URL.Query().Get("maxResults")
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query with page size", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPageSize(100).Execute(&res)
+
+			Convey("Then page size is used for both requests", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.MaxResults, ShouldEqual, 100)
+				So(maxResults, ShouldEqual, "100")
+				So(len(res), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When execute a query with invalid page size", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPageSize(0).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid page size")
+			})
+		})
+	})
+}
+
+// newFakeJobClient generates a client whose service runs a query job and records the inserted job
+func newFakeJobClient(sent *bigquery.Job) *Client {
+	return newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			json.NewDecoder(req.Body).Decode(sent)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "RUNNING"}
+			}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{
+			"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+			"jobComplete": true,
+			"totalRows": "1",
+			"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+			"rows": [{"f": [{"v": "test_name"}]}]
+		}`), nil
+	}))
+}
+
+func TestSetPriority(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query with batch priority", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPriority(PriorityBatch).Execute(&res)
+
+			Convey("Then the query is inserted as a batch job", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration, ShouldNotBeNil)
+				So(sent.Configuration.Query.Priority, ShouldEqual, "BATCH")
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+
+		Convey("When execute a query with interactive priority and job configuration", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").
+				SetPriority(PriorityInteractive).
+				SetJobConfig(&JobConfiguration{TempTableName: "temp_table"}).
+				Execute(&res)
+
+			Convey("Then the query is inserted as an interactive job", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.Query.Priority, ShouldEqual, "INTERACTIVE")
+			})
+		})
+	})
+}
+
+func TestSetLabels(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query with labels", func() {
+			var res []nameRec
+			labels := map[string]string{"team": "analytics", "pipeline": "daily-report_v2"}
+			err := c.Query("SELECT name FROM test").SetLabels(labels).Execute(&res)
+
+			Convey("Then the query is inserted as a job with labels", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration, ShouldNotBeNil)
+				So(sent.Configuration.Labels, ShouldResemble, labels)
+			})
+		})
+
+		Convey("When execute a query with an invalid label key", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetLabels(map[string]string{"Team": "analytics"}).Execute(&res)
+
+			Convey("Then err is returned without a request", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `Invalid label key: "Team"`)
+				So(sent.Configuration, ShouldBeNil)
+			})
+		})
+
+		Convey("When execute a query with an invalid label value", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetLabels(map[string]string{"team": strings.Repeat("a", 64)}).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, `Invalid label value for "team"`)
+			})
+		})
+	})
+}
+
+func TestUseQueryCache(t *testing.T) {
+	Convey("Given a client whose service runs a query", t, func() {
+		var queryRequest bigquery.QueryRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&queryRequest)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "0",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]}
+			}`), nil
+		}))
+
+		Convey("When execute a query without cache", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").UseQueryCache(false).Execute(&res)
+
+			Convey("Then the query request disables cache", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.UseQueryCache, ShouldNotBeNil)
+				So(*queryRequest.UseQueryCache, ShouldBeFalse)
+			})
+		})
+
+		Convey("When execute a query by default", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then the query request leaves cache to bigquery", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.UseQueryCache, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query job without cache", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").UseQueryCache(false).SetPriority(PriorityBatch).Execute(&res)
+
+			Convey("Then the job disables cache", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.Query.UseQueryCache, ShouldNotBeNil)
+				So(*sent.Configuration.Query.UseQueryCache, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestUseLegacySQL(t *testing.T) {
+	Convey("Given a client whose service runs a query", t, func() {
+		var queryRequest bigquery.QueryRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&queryRequest)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "0",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]}
+			}`), nil
+		}))
+
+		Convey("When execute a query in standard SQL", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM `test`").UseLegacySQL(false).Execute(&res)
+
+			Convey("Then the query request disables legacy SQL", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.UseLegacySql, ShouldNotBeNil)
+				So(*queryRequest.UseLegacySql, ShouldBeFalse)
+			})
+		})
+
+		Convey("When execute a query by default", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM [test]").Execute(&res)
+
+			Convey("Then the query request leaves the dialect to bigquery", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.UseLegacySql, ShouldBeNil)
+			})
+		})
+
+		Convey("When execute a query with parameters in legacy SQL", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM [test] WHERE age > @age").Param("age", 20).UseLegacySQL(true).Execute(&res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Query parameters are not supported in legacy SQL")
+			})
+		})
+	})
+
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query job with the dialect of job configuration", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM `test`").
+				SetJobConfig(&JobConfiguration{UseLegacySQL: googleapi.Bool(false)}).
+				Execute(&res)
+
+			Convey("Then the job disables legacy SQL", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.Query.UseLegacySql, ShouldNotBeNil)
+				So(*sent.Configuration.Query.UseLegacySql, ShouldBeFalse)
+			})
+		})
+
+		Convey("When execute a query job overriding the dialect of job configuration", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM [test]").
+				SetJobConfig(&JobConfiguration{UseLegacySQL: googleapi.Bool(false)}).
+				UseLegacySQL(true).
+				Execute(&res)
+
+			Convey("Then the job enables legacy SQL", func() {
+				So(err, ShouldBeNil)
+				So(*sent.Configuration.Query.UseLegacySql, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestSetMaxBytesBilled(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query with max bytes billed", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetMaxBytesBilled(10485760).Execute(&res)
+
+			Convey("Then the query is inserted as a job with the limit", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration, ShouldNotBeNil)
+				So(sent.Configuration.Query.MaximumBytesBilled, ShouldEqual, 10485760)
+			})
+		})
+	})
+
+	Convey("Given a client whose service fails a query job exceeding the limit", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				return jsonResponse(http.StatusOK, `{"jobReference": {"projectId": "winter_test00", "jobId": "job_1"}}`), nil
+			}
+			return jsonResponse(http.StatusBadRequest, `{"error": {"code": 400, "message": "Query exceeded limit for bytes billed: 1000. 10485760 or higher required."}}`), nil
+		}))
+
+		Convey("When execute a query with max bytes billed", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetMaxBytesBilled(1000).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "Query exceeded limit for bytes billed")
+			})
+		})
+	})
+}
+
+func TestSetLocation(t *testing.T) {
+	Convey("Given a client whose service completes a query on GetQueryResults", t, func() {
+		var queryRequest bigquery.QueryRequest
+		var location string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				json.NewDecoder(req.Body).Decode(&queryRequest)
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": false
+				}`), nil
+			}
+			location = req.URL.Query().Get("location")
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query in a location", func() {
+			var res []nameRec
+			err := c.SetLocation("asia-northeast1").Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then the location is threaded into the requests", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.Location, ShouldEqual, "asia-northeast1")
+				So(location, ShouldEqual, "asia-northeast1")
+				So(len(res), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query job in a location", func() {
+			var res []nameRec
+			err := c.SetLocation("EU").Query("SELECT name FROM test").SetPriority(PriorityBatch).Execute(&res)
+
+			Convey("Then the job is inserted in the location", func() {
+				So(err, ShouldBeNil)
+				So(sent.JobReference, ShouldNotBeNil)
+				So(sent.JobReference.Location, ShouldEqual, "EU")
+			})
+		})
+	})
+}
+
+func TestRowCount(t *testing.T) {
+	Convey("Given a client whose service completes a query with many rows", t, func() {
+		var queryRequest map[string]interface{}
+		var requests int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			requests++
+			json.NewDecoder(req.Body).Decode(&queryRequest)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "12345",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"pageToken": "page_2"
+			}`), nil
+		}))
+
+		Convey("When count rows of a query", func() {
+			count, err := c.Query("SELECT name FROM test").RowCount()
+
+			Convey("Then total rows are returned without pagination", func() {
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 12345)
+				So(requests, ShouldEqual, 1)
+				So(queryRequest["maxResults"], ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestExecuteStream(t *testing.T) {
+	Convey("Given a client whose service returns results in three pages", t, func() {
+		var requests int
+		c := newFakePagedClient([]string{"test_name1", "test_name2", "test_name3"}, &requests)
+
+		Convey("When execute a query with a channel of structs", func() {
+			out := make(chan []nameRec)
+			errChan, err := c.Query("SELECT name FROM test").ExecuteStream(out)
+			So(err, ShouldBeNil)
+
+			var pages [][]nameRec
+			for page := range out {
+				pages = append(pages, page)
+			}
+
+			Convey("Then converted pages are sent and channels are closed", func() {
+				So(pages, ShouldResemble, [][]nameRec{
+					{{Name: "test_name1"}},
+					{{Name: "test_name2"}},
+					{{Name: "test_name3"}},
+				})
+				err, ok := <-errChan
+				So(err, ShouldBeNil)
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When execute a query with a channel of structs not matching the schema", func() {
+			out := make(chan []convertRec)
+			errChan, err := c.Query("SELECT name FROM test").ExecuteStream(out)
+			So(err, ShouldBeNil)
+
+			var pages int
+			for range out {
+				pages++
+			}
+
+			Convey("Then err is sent and channels are closed", func() {
+				So(pages, ShouldEqual, 0)
+				So(<-errChan, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When execute a query with a non channel", func() {
+			_, err := c.Query("SELECT name FROM test").ExecuteStream([]nameRec{})
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Not channel of slice")
+			})
+		})
+	})
+}
+
+func TestConvert(t *testing.T) {
+	Convey("Given invalid fields", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "name",
+				Type:        "STRING",
+			},
+			{
 				Description: "",
 				Fields:      nil,
 				Mode:        "NULLABLE",
@@ -251,13 +1097,6 @@ func TestConvert(t *testing.T) {
 				Name:        "timestamp",
 				Type:        "TIMESTAMP",
 			},
-			{
-				Description: "",
-				Fields:      nil,
-				Mode:        "NULLABLE",
-				Name:        "idDeleted",
-				Type:        "BOOLEAN",
-			},
 		}
 
 		rows := []*bigquery.TableRow{
@@ -275,9 +1114,6 @@ func TestConvert(t *testing.T) {
 					{
 						V: "1.422943323461E9",
 					},
-					{
-						V: "true",
-					},
 				},
 			},
 		}
@@ -287,15 +1123,1801 @@ func TestConvert(t *testing.T) {
 		Convey("When convert bigquery data", func() {
 			err := Convert(fields, rows, &res)
 
-			Convey("Then results are set into a given receiver", func() {
-				So(err, ShouldBeNil)
-				So(len(res), ShouldEqual, 1)
-				So(res[0].Name, ShouldEqual, "test_name")
-				So(res[0].Age, ShouldEqual, 26)
-				So(res[0].Score, ShouldEqual, 12.34)
-				So(res[0].Timestamp, ShouldEqual, 1422943323)
-				So(res[0].IsDeleted, ShouldEqual, true)
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Field IsDeleted (bool) at index 4 has no column to map")
+
+			})
+		})
+	})
 
+	Convey("Given bigquery fields, rows and result receiver containing nil", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "name",
+				Type:        "STRING",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "age",
+				Type:        "INTEGER",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "score",
+				Type:        "FLOAT",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "timestamp",
+				Type:        "TIMESTAMP",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "idDeleted",
+				Type:        "BOOLEAN",
+			},
+		}
+
+		rows := []*bigquery.TableRow{
+			&bigquery.TableRow{
+				F: []*bigquery.TableCell{
+					{
+						V: nil,
+					},
+					{
+						V: nil,
+					},
+					{
+						V: nil,
+					},
+					{
+						V: nil,
+					},
+					{
+						V: nil,
+					},
+				},
+			},
+		}
+
+		res := []convertRec{}
+
+		Convey("When convert bigquery data", func() {
+			err := Convert(fields, rows, &res)
+
+			Convey("Then results resolved nil are set into a given receiver", func() {
+				So(err, ShouldBeNil)
+				So(len(res), ShouldEqual, 1)
+				So(res[0].Name, ShouldEqual, "")
+				So(res[0].Age, ShouldEqual, 0)
+				So(res[0].Score, ShouldEqual, 0.0)
+				So(res[0].Timestamp, ShouldEqual, 0)
+				So(res[0].IsDeleted, ShouldEqual, false)
+
+			})
+		})
+	})
+
+	Convey("Given bigquery fields, rows and result receiver", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "name",
+				Type:        "STRING",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "age",
+				Type:        "INTEGER",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "score",
+				Type:        "FLOAT",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "timestamp",
+				Type:        "TIMESTAMP",
+			},
+			{
+				Description: "",
+				Fields:      nil,
+				Mode:        "NULLABLE",
+				Name:        "idDeleted",
+				Type:        "BOOLEAN",
+			},
+		}
+
+		rows := []*bigquery.TableRow{
+			&bigquery.TableRow{
+				F: []*bigquery.TableCell{
+					{
+						V: "test_name",
+					},
+					{
+						V: "26",
+					},
+					{
+						V: "12.34",
+					},
+					{
+						V: "1.422943323461E9",
+					},
+					{
+						V: "true",
+					},
+				},
+			},
+		}
+
+		res := []convertRec{}
+
+		Convey("When convert bigquery data", func() {
+			err := Convert(fields, rows, &res)
+
+			Convey("Then results are set into a given receiver", func() {
+				So(err, ShouldBeNil)
+				So(len(res), ShouldEqual, 1)
+				So(res[0].Name, ShouldEqual, "test_name")
+				So(res[0].Age, ShouldEqual, 26)
+				So(res[0].Score, ShouldEqual, 12.34)
+				So(res[0].Timestamp, ShouldEqual, 1422943323461000)
+				So(res[0].IsDeleted, ShouldEqual, true)
+
+			})
+		})
+	})
+}
+
+func TestConvertBoolean(t *testing.T) {
+	type boolRec struct {
+		IsDeleted bool
+	}
+
+	Convey("Given a boolean field", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "isDeleted", Type: "BOOLEAN"},
+		}
+		newRows := func(values ...string) []*bigquery.TableRow {
+			rows := make([]*bigquery.TableRow, 0, len(values))
+			for _, v := range values {
+				rows = append(rows, &bigquery.TableRow{F: []*bigquery.TableCell{{V: v}}})
+			}
+			return rows
+		}
+
+		Convey("When convert booleans in various cases", func() {
+			res := []boolRec{}
+			err := Convert(fields, newRows("TRUE", "False", "true", "0", "1"), &res)
+
+			Convey("Then booleans are parsed case-insensitively", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []boolRec{{true}, {false}, {true}, {false}, {true}})
+			})
+		})
+
+		Convey("When convert an invalid boolean", func() {
+			res := []boolRec{}
+			err := Convert(fields, newRows("yes"), &res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid boolean format")
+			})
+		})
+	})
+}
+
+func TestConvertFloatSpecialValues(t *testing.T) {
+	type floatRec struct {
+		Score float64
+	}
+
+	Convey("Given a float field with special values", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "score", Type: "FLOAT"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "NaN"}}},
+			{F: []*bigquery.TableCell{{V: "Infinity"}}},
+			{F: []*bigquery.TableCell{{V: "-Infinity"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []floatRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then special values are mapped into float64", func() {
+				So(err, ShouldBeNil)
+				So(len(res), ShouldEqual, 3)
+				So(math.IsNaN(res[0].Score), ShouldBeTrue)
+				So(math.IsInf(res[1].Score, 1), ShouldBeTrue)
+				So(math.IsInf(res[2].Score, -1), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestLenientTypes(t *testing.T) {
+	type lenientRec struct {
+		Count int
+		ID    string
+	}
+
+	Convey("Given a client whose service returns a numeric string and an integer", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [
+					{"name": "count", "type": "STRING"},
+					{"name": "id", "type": "INTEGER"}
+				]},
+				"rows": [{"f": [{"v": "42"}, {"v": "1001"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query strictly", func() {
+			res := []lenientRec{}
+			err := c.Query("SELECT count, id FROM test").Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				_, ok := err.(*ConvertError)
+				So(ok, ShouldBeTrue)
+			})
+		})
+
+		Convey("When execute a query with lenient types", func() {
+			res := []lenientRec{}
+			err := c.Query("SELECT count, id FROM test").LenientTypes().Execute(&res)
+
+			Convey("Then cells are coerced into the kinds of fields", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []lenientRec{{Count: 42, ID: "1001"}})
+			})
+		})
+	})
+}
+
+func TestJobConfigDestinationTable(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query into a partitioned destination table", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{
+				TempTableName:    "temp_table",
+				TimePartitioning: &TimePartitioning{Type: PartitionHour, Field: "created_at"},
+			}).Execute(&res)
+
+			Convey("Then the partitioning is sent with the job", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.Query.DestinationTable.TableId, ShouldEqual, "temp_table")
+				So(sent.Configuration.Query.TimePartitioning, ShouldResemble, &bigquery.TimePartitioning{
+					Type:  "HOUR",
+					Field: "created_at",
+				})
+			})
+		})
+
+		Convey("When execute a query into a clustered destination table", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{
+				TempTableName: "temp_table",
+				Clustering:    []string{"name"},
+			}).Execute(&res)
+
+			Convey("Then the clustering is sent with the job", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.Query.Clustering, ShouldResemble, &bigquery.Clustering{Fields: []string{"name"}})
+			})
+		})
+
+		Convey("When execute a query into a table of another dataset", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{
+				TempTableName:      "temp_table",
+				DestinationProject: "scratch_project",
+				DestinationDataset: "scratch",
+			}).Execute(&res)
+
+			Convey("Then the destination table is in the given dataset", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.Query.DestinationTable, ShouldResemble, &bigquery.TableReference{
+					ProjectId: "scratch_project",
+					DatasetId: "scratch",
+					TableId:   "temp_table",
+				})
+				So(sent.Configuration.Query.DefaultDataset.DatasetId, ShouldEqual, "bq_test")
+			})
+		})
+
+		Convey("When execute a query into a table of another dataset in the same project", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{
+				TempTableName:      "temp_table",
+				DestinationDataset: "scratch",
+			}).Execute(&res)
+
+			Convey("Then the destination table is in the project of the client", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.Query.DestinationTable, ShouldResemble, &bigquery.TableReference{
+					ProjectId: "winter_test00",
+					DatasetId: "scratch",
+					TableId:   "temp_table",
+				})
+			})
+		})
+	})
+}
+
+func TestTempTableExpiration(t *testing.T) {
+	Convey("Given a client whose service runs a query job into a temp table", t, func() {
+		var requests []string
+		var patched bigquery.Table
+		deleted := http.StatusNoContent
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			switch req.Method {
+			case http.MethodPost:
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"status": {"state": "RUNNING"}
+				}`), nil
+			case http.MethodPatch:
+				json.NewDecoder(req.Body).Decode(&patched)
+				return jsonResponse(http.StatusOK, `{}`), nil
+			case http.MethodDelete:
+				return jsonResponse(deleted, `{}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query with an expiration of the temp table", func() {
+			var res []nameRec
+			before := time.Now()
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{
+				TempTableName:       "temp_table",
+				DestinationDataset:  "scratch",
+				TempTableExpiration: time.Hour,
+			}).Execute(&res)
+
+			Convey("Then the expiration is set on the temp table", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+				So(requests, ShouldContain, "PATCH /bigquery/v2/projects/winter_test00/datasets/scratch/tables/temp_table")
+				expiration := time.Unix(0, patched.ExpirationTime*int64(time.Millisecond))
+				So(expiration, ShouldHappenOnOrBetween, before.Add(time.Hour).Truncate(time.Millisecond), time.Now().Add(time.Hour))
+			})
+		})
+
+		Convey("When execute a query without an expiration of the temp table", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{TempTableName: "temp_table"}).Execute(&res)
+
+			Convey("Then the temp table is not patched", func() {
+				So(err, ShouldBeNil)
+				So(patched.ExpirationTime, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When clean up the temp table", func() {
+			q := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{TempTableName: "temp_table"})
+			err := q.CleanupTempTable()
+
+			Convey("Then the temp table is deleted", func() {
+				So(err, ShouldBeNil)
+				So(requests, ShouldResemble, []string{"DELETE /bigquery/v2/projects/winter_test00/datasets/bq_test/tables/temp_table"})
+			})
+		})
+
+		Convey("When clean up the temp table already deleted", func() {
+			deleted = http.StatusNotFound
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{TempTableName: "temp_table"}).CleanupTempTable()
+
+			Convey("Then err is not returned", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When clean up without a temp table", func() {
+			err := c.Query("SELECT name FROM test").CleanupTempTable()
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "No temp table")
+			})
+		})
+	})
+}
+
+// contextKey is a key of a context value to check the context of requests
+type contextKey string
+
+func TestExecuteContext(t *testing.T) {
+	Convey("Given a client whose service returns results in three pages", t, func() {
+		var requests int
+		var values []interface{}
+		var cancel context.CancelFunc
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			requests++
+			values = append(values, req.Context().Value(contextKey("trace")))
+			if requests == 2 && cancel != nil {
+				cancel()
+			}
+			body := `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "3",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name` + strconv.Itoa(requests) + `"}]}]`
+			if requests < 3 {
+				body += `, "pageToken": "page_` + strconv.Itoa(requests+1) + `"`
+			}
+			return jsonResponse(http.StatusOK, body+`}`), nil
+		}))
+
+		Convey("When execute a query with a context", func() {
+			ctx := context.WithValue(context.Background(), contextKey("trace"), "trace_1")
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").ExecuteContext(ctx, &res)
+
+			Convey("Then all requests are sent with the context", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldHaveLength, 3)
+				So(values, ShouldResemble, []interface{}{"trace_1", "trace_1", "trace_1"})
+			})
+		})
+
+		Convey("When the context is canceled during paging", func() {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").ExecuteContext(ctx, &res)
+
+			Convey("Then paging stops with the error of the context", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(requests, ShouldEqual, 2)
+				So(res, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When execute a query with a canceled context", func() {
+			ctx, cancelNow := context.WithCancel(context.Background())
+			cancelNow()
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").ExecuteContext(ctx, &res)
+
+			Convey("Then the error of the context is returned", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(requests, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestSetTimeout(t *testing.T) {
+	Convey("Given a client whose service reports an incomplete query twice", t, func() {
+		var timeouts []string
+		var queryRequest bigquery.QueryRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				json.NewDecoder(req.Body).Decode(&queryRequest)
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": false
+				}`), nil
+			}
+			timeouts = append(timeouts, req.URL.Query().Get("timeoutMs"))
+			if len(timeouts) < 2 {
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": false
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+		c.pollInterval = time.Millisecond
+
+		Convey("When execute a query with a timeout", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetTimeout(time.Minute).Execute(&res)
+
+			Convey("Then results are gathered after the query completes", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.TimeoutMs, ShouldEqual, 60000)
+				So(len(timeouts), ShouldEqual, 2)
+				So(timeouts[0], ShouldNotBeEmpty)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+
+		Convey("When set an invalid timeout", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetTimeout(0).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid timeout")
+			})
+		})
+	})
+
+	Convey("Given a client whose service never completes a query", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": false
+			}`), nil
+		}))
+		c.pollInterval = 5 * time.Millisecond
+
+		Convey("When execute a query with a timeout", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetTimeout(20 * time.Millisecond).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldEqual, ErrQueryTimeout)
+			})
+		})
+	})
+}
+
+func TestExecuteWithoutSchema(t *testing.T) {
+	Convey("Given a client whose service completes a DML statement without schema", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"numDmlAffectedRows": "3"
+			}`), nil
+		}))
+
+		Convey("When execute the statement", func() {
+			var res []nameRec
+			err := c.Query("UPDATE test SET name = 'test_name' WHERE true").Execute(&res)
+
+			Convey("Then no rows are returned without panic", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When execute the statement with a channel", func() {
+			resChan := make(chan ResponseData)
+			c.Query("UPDATE test SET name = 'test_name' WHERE true").ExecuteWithChannel(resChan)
+
+			Convey("Then a page without fields is received", func() {
+				data := <-resChan
+				So(data.Err, ShouldBeNil)
+				So(data.Fields, ShouldBeEmpty)
+				So(data.Rows, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When get the schema of the statement", func() {
+			fields, err := c.Query("UPDATE test SET name = 'test_name' WHERE true").Schema()
+
+			Convey("Then empty fields are returned", func() {
+				So(err, ShouldBeNil)
+				So(fields, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestExecuteWithChannel(t *testing.T) {
+	Convey("Given a client whose service fails on the second page", t, func() {
+		var requests int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			requests++
+			if requests > 1 {
+				return jsonResponse(http.StatusBadRequest, `{"error": {"code": 400, "message": "Invalid page"}}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "2",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name1"}]}],
+				"pageToken": "page_2"
+			}`), nil
+		}))
+
+		Convey("When execute a query with a channel", func() {
+			resChan := make(chan ResponseData)
+			c.Query("SELECT name FROM test").ExecuteWithChannel(resChan)
+
+			var pages []ResponseData
+			for data := range resChan {
+				pages = append(pages, data)
+			}
+
+			Convey("Then the error is sent and the channel is closed", func() {
+				So(pages, ShouldHaveLength, 2)
+				So(pages[0].Err, ShouldBeNil)
+				So(pages[0].Rows, ShouldHaveLength, 1)
+				So(pages[1].Err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When execute a misconfigured query with a channel", func() {
+			resChan := make(chan ResponseData, 1)
+			c.Query("SELECT name FROM test").SetConcurrency(0).ExecuteWithChannel(resChan)
+
+			data, ok := <-resChan
+			_, open := <-resChan
+
+			Convey("Then the error is sent and the channel is closed", func() {
+				So(ok, ShouldBeTrue)
+				So(data.Err, ShouldNotBeNil)
+				So(open, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestExecuteWithChannelStats(t *testing.T) {
+	Convey("Given a client whose service returns results in two pages with statistics", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case req.Method == http.MethodPost:
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": true,
+					"totalRows": "2",
+					"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+					"rows": [{"f": [{"v": "test_name1"}]}],
+					"pageToken": "page_2"
+				}`), nil
+			case strings.Contains(req.URL.Path, "/queries/"):
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": true,
+					"totalRows": "2",
+					"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+					"rows": [{"f": [{"v": "test_name2"}]}]
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "DONE"},
+				"statistics": {"query": {"totalBytesProcessed": "1024", "totalBytesBilled": "10485760", "cacheHit": false}}
+			}`), nil
+		}))
+
+		Convey("When execute a query with a channel", func() {
+			resChan := make(chan ResponseData)
+			c.Query("SELECT name FROM test").ExecuteWithChannel(resChan)
+
+			var pages []ResponseData
+			for data := range resChan {
+				pages = append(pages, data)
+			}
+
+			Convey("Then the last data carries the row count and statistics", func() {
+				So(pages, ShouldHaveLength, 2)
+				So(pages[0].Err, ShouldBeNil)
+				So(pages[0].TotalRows, ShouldEqual, 0)
+				So(pages[0].Stats, ShouldBeNil)
+				So(pages[1].Err, ShouldBeNil)
+				So(pages[1].TotalRows, ShouldEqual, 2)
+				So(pages[1].Stats, ShouldResemble, &QueryStats{TotalBytesProcessed: 1024, TotalBytesBilled: 10485760})
+			})
+		})
+	})
+}
+
+func TestExecuteDML(t *testing.T) {
+	Convey("Given a client whose service completes a DML statement", t, func() {
+		var queryRequest bigquery.QueryRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				json.NewDecoder(req.Body).Decode(&queryRequest)
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": false
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"numDmlAffectedRows": "42"
+			}`), nil
+		}))
+
+		Convey("When execute the statement", func() {
+			affected, err := c.Query("DELETE FROM test WHERE age < 20").ExecuteDML()
+
+			Convey("Then the number of affected rows is returned", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.Query, ShouldEqual, "DELETE FROM test WHERE age < 20")
+				So(affected, ShouldEqual, 42)
+			})
+		})
+	})
+}
+
+func TestConvertPointers(t *testing.T) {
+	Convey("Given bigquery fields and rows", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "age", Type: "INTEGER"},
+			{Mode: "NULLABLE", Name: "score", Type: "FLOAT"},
+			{Mode: "NULLABLE", Name: "timestamp", Type: "TIMESTAMP"},
+			{Mode: "NULLABLE", Name: "isDeleted", Type: "BOOLEAN"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "test_name1"}, {V: "26"}, {V: "12.5"}, {V: "1.422943323461E9"}, {V: "false"}}},
+			{F: []*bigquery.TableCell{{V: "test_name2"}, {V: "31"}, {V: "0.5"}, {V: "1.422943323461E9"}, {V: "true"}}},
+		}
+
+		Convey("When convert into a slice of pointers", func() {
+			res := []*convertRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then each element points to a distinct converted struct", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []*convertRec{
+					{Name: "test_name1", Age: 26, Score: 12.5, Timestamp: 1422943323461000, IsDeleted: false},
+					{Name: "test_name2", Age: 31, Score: 0.5, Timestamp: 1422943323461000, IsDeleted: true},
+				})
+				So(res[0], ShouldNotPointTo, res[1])
+			})
+		})
+	})
+}
+
+func TestQueryInProject(t *testing.T) {
+	Convey("Given a client without dataset", t, func() {
+		var path string
+		var queryRequest bigquery.QueryRequest
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+		c.service, _ = bigquery.New(&http.Client{Transport: fakeTransport(func(req *http.Request) (*http.Response, error) {
+			path = req.URL.Path
+			json.NewDecoder(req.Body).Decode(&queryRequest)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "billing_project", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		})})
+
+		Convey("When execute a fully qualified query in a project", func() {
+			var res []nameRec
+			err := c.QueryInProject("billing_project", "SELECT name FROM `other_project.bq_test.test`").Execute(&res)
+
+			Convey("Then the query is billed to the project without default dataset", func() {
+				So(err, ShouldBeNil)
+				So(path, ShouldContainSubstring, "/projects/billing_project/queries")
+				So(queryRequest.DefaultDataset, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+
+		Convey("When execute a query without project", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "No project")
+			})
+		})
+	})
+}
+
+func TestSetBillingProject(t *testing.T) {
+	Convey("Given a client with a billing project separate from the dataset", t, func() {
+		var paths []string
+		var queryRequest bigquery.QueryRequest
+		var job bigquery.Job
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path)
+			if strings.HasSuffix(req.URL.Path, "/jobs") {
+				json.NewDecoder(req.Body).Decode(&job)
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "billing_project", "jobId": "job_1"},
+					"status": {"state": "RUNNING"}
+				}`), nil
+			}
+			if req.Method == http.MethodPost {
+				json.NewDecoder(req.Body).Decode(&queryRequest)
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "billing_project", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+		c.SetBillingProject("billing_project")
+
+		Convey("When execute a query", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then the query runs in the billing project with the dataset as default", func() {
+				So(err, ShouldBeNil)
+				So(paths, ShouldResemble, []string{"/bigquery/v2/projects/billing_project/queries"})
+				So(queryRequest.DefaultDataset, ShouldResemble, &bigquery.DatasetReference{ProjectId: "winter_test00", DatasetId: "bq_test"})
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+
+		Convey("When execute a query into a temp table", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{TempTableName: "temp_table"}).Execute(&res)
+
+			Convey("Then the job runs in the billing project and writes into the dataset", func() {
+				So(err, ShouldBeNil)
+				So(paths[0], ShouldEqual, "/bigquery/v2/projects/billing_project/jobs")
+				So(paths[1], ShouldEqual, "/bigquery/v2/projects/billing_project/queries/job_1")
+				So(job.Configuration.Query.DefaultDataset.ProjectId, ShouldEqual, "winter_test00")
+				So(job.Configuration.Query.DestinationTable, ShouldResemble, &bigquery.TableReference{
+					ProjectId: "winter_test00",
+					DatasetId: "bq_test",
+					TableId:   "temp_table",
+				})
+			})
+		})
+	})
+}
+
+func TestFlattenResults(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query without flattening results into a destination table", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM [winter_test00:bq_test.test]").
+				FlattenResults(false).
+				SetJobConfig(&JobConfiguration{AllowLargeResults: true, TempTableName: "temp_table"}).
+				Execute(&res)
+
+			Convey("Then the query is inserted as a job without flattening", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration, ShouldNotBeNil)
+				So(sent.Configuration.Query.FlattenResults, ShouldNotBeNil)
+				So(*sent.Configuration.Query.FlattenResults, ShouldBeFalse)
+			})
+		})
+
+		Convey("When execute a query flattening results", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM [winter_test00:bq_test.test]").FlattenResults(true).Execute(&res)
+
+			Convey("Then the query is inserted as a job with flattening", func() {
+				So(err, ShouldBeNil)
+				So(*sent.Configuration.Query.FlattenResults, ShouldBeTrue)
+			})
+		})
+
+		Convey("When execute a query without flattening results and large results", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM [winter_test00:bq_test.test]").
+				FlattenResults(false).
+				SetJobConfig(&JobConfiguration{TempTableName: "temp_table"}).
+				Execute(&res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "requires AllowLargeResults and TempTableName")
+			})
+		})
+
+		Convey("When execute a query without flattening results and job configuration", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM [winter_test00:bq_test.test]").FlattenResults(false).Execute(&res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "requires AllowLargeResults and TempTableName")
+			})
+		})
+	})
+}
+
+func TestConvertGeography(t *testing.T) {
+	type placeRec struct {
+		Name     string
+		Location string
+	}
+
+	Convey("Given a geography field", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "location", Type: "GEOGRAPHY"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "tokyo"}, {V: "POINT(139.69 35.68)"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []placeRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then WKT is mapped into string", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []placeRec{{Name: "tokyo", Location: "POINT(139.69 35.68)"}})
+			})
+		})
+
+		Convey("When convert bigquery data into a non-string field", func() {
+			res := []struct {
+				Name     string
+				Location float64
+			}{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column 'location' (GEOGRAPHY) at index 1 cannot map to field Location (float64)")
+			})
+		})
+	})
+}
+
+type testStatus string
+
+type testPriority int
+
+type testLevel int32
+
+type testRatio float64
+
+type testFlag bool
+
+type testMicros int64
+
+func TestConvertNamedTypes(t *testing.T) {
+	type taskRec struct {
+		Status    testStatus
+		Priority  testPriority
+		Level     testLevel
+		Ratio     testRatio
+		Done      testFlag
+		CreatedAt testMicros
+	}
+
+	Convey("Given fields of scalar types", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "status", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "priority", Type: "INTEGER"},
+			{Mode: "NULLABLE", Name: "level", Type: "INTEGER"},
+			{Mode: "NULLABLE", Name: "ratio", Type: "FLOAT"},
+			{Mode: "NULLABLE", Name: "done", Type: "BOOLEAN"},
+			{Mode: "NULLABLE", Name: "created_at", Type: "TIMESTAMP"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "running"}, {V: "2"}, {V: "-3"}, {V: "0.5"}, {V: "true"}, {V: "1.422943323461E9"}}},
+		}
+
+		Convey("When convert bigquery data into fields of named types", func() {
+			res := []taskRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then values are set into the named types", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []taskRec{{
+					Status:    testStatus("running"),
+					Priority:  testPriority(2),
+					Level:     testLevel(-3),
+					Ratio:     testRatio(0.5),
+					Done:      testFlag(true),
+					CreatedAt: testMicros(1422943323461000),
+				}})
+			})
+		})
+	})
+
+	Convey("Given an integer out of the range of int32", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "level", Type: "INTEGER"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "2147483648"}}},
+		}
+		type levelRec struct {
+			Level int32
+		}
+
+		Convey("When convert it into an int32 field", func() {
+			res := []levelRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then a ConvertError is returned instead of wrapping the value", func() {
+				So(err, ShouldNotBeNil)
+				convertErr, ok := err.(*ConvertError)
+				So(ok, ShouldBeTrue)
+				So(convertErr.Column, ShouldEqual, "level")
+				So(convertErr.Kind, ShouldEqual, reflect.Int32)
+			})
+		})
+
+		Convey("When convert it as a string into an int32 field leniently", func() {
+			res := []levelRec{}
+			err := convert([]*bigquery.TableFieldSchema{{Name: "level", Type: "STRING"}}, rows, &res, &convertOptions{lenient: true})
+
+			Convey("Then a ConvertError is returned", func() {
+				_, ok := err.(*ConvertError)
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestExecuteWithHugeTotalRows(t *testing.T) {
+	Convey("Given a client whose service reports huge total rows", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "18446744073709551615",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When retrieve rows of a query", func() {
+			_, rows, err := c.Query("SELECT name FROM test").SetPageSize(10).retrieveRows(context.Background(), nil)
+
+			Convey("Then rows are preallocated no more than the page size", func() {
+				So(err, ShouldBeNil)
+				So(len(rows), ShouldEqual, 1)
+				So(cap(rows), ShouldBeLessThanOrEqualTo, 10)
+			})
+		})
+	})
+}
+
+func TestSetQueryTimeout(t *testing.T) {
+	Convey("Given a client whose service completes a query", t, func() {
+		var queryRequest bigquery.QueryRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&queryRequest)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query with a query timeout", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetQueryTimeout(30000).Execute(&res)
+
+			Convey("Then the timeout is sent with the query request", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.TimeoutMs, ShouldEqual, 30000)
+			})
+		})
+
+		Convey("When execute a query with a query timeout and a timeout", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetTimeout(time.Minute).SetQueryTimeout(30000).Execute(&res)
+
+			Convey("Then the query timeout is sent with the query request", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest.TimeoutMs, ShouldEqual, 30000)
+			})
+		})
+
+		Convey("When set an invalid query timeout", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetQueryTimeout(-1).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid query timeout")
+			})
+		})
+	})
+}
+
+func TestIgnoreUnknownColumns(t *testing.T) {
+	type partialRec struct {
+		Name  string
+		Score float64
+	}
+
+	Convey("Given a client whose service returns five columns", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [
+					{"name": "name", "type": "STRING"},
+					{"name": "age", "type": "INTEGER"},
+					{"name": "score", "type": "FLOAT"},
+					{"name": "timestamp", "type": "TIMESTAMP"},
+					{"name": "isDeleted", "type": "BOOLEAN"}
+				]},
+				"rows": [{"f": [{"v": "test_name"}, {"v": "26"}, {"v": "12.5"}, {"v": "1.422943323461E9"}, {"v": "false"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query ignoring unknown columns into a struct of two fields", func() {
+			res := []partialRec{}
+			err := c.Query("SELECT * FROM test").IgnoreUnknownColumns().Execute(&res)
+
+			Convey("Then only columns of the fields are converted", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []partialRec{{Name: "test_name", Score: 12.5}})
+			})
+		})
+
+		Convey("When execute a query into a struct of two fields", func() {
+			res := []partialRec{}
+			err := c.Query("SELECT * FROM test").Execute(&res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column 'score' (FLOAT) at index 2 has no field to map")
+			})
+		})
+	})
+}
+
+func TestConvertInterval(t *testing.T) {
+	type intervalRec struct {
+		Name     string
+		Duration string
+	}
+
+	Convey("Given an interval field", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "duration", Type: "INTERVAL"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "test_name"}, {V: "1-2 3 4:5:6.789"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []intervalRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then the canonical format is mapped into string", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []intervalRec{{Name: "test_name", Duration: "1-2 3 4:5:6.789"}})
+			})
+		})
+	})
+}
+
+func TestConvertJSON(t *testing.T) {
+	type payload struct {
+		Action string   `json:"action"`
+		Tags   []string `json:"tags"`
+	}
+
+	Convey("Given a json field", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "payload", Type: "JSON"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "test_name"}, {V: `{"action":"click","tags":["a","b"],"meta":{"x":1}}`}}},
+		}
+
+		Convey("When convert bigquery data into a map", func() {
+			res := []struct {
+				Name    string
+				Payload map[string]interface{}
+			}{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then the json is unmarshaled into a nested map", func() {
+				So(err, ShouldBeNil)
+				So(res[0].Payload, ShouldResemble, map[string]interface{}{
+					"action": "click",
+					"tags":   []interface{}{"a", "b"},
+					"meta":   map[string]interface{}{"x": float64(1)},
+				})
+			})
+		})
+
+		Convey("When convert bigquery data into a struct", func() {
+			res := []struct {
+				Name    string
+				Payload payload
+			}{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then the json is unmarshaled into the struct", func() {
+				So(err, ShouldBeNil)
+				So(res[0].Payload, ShouldResemble, payload{Action: "click", Tags: []string{"a", "b"}})
+			})
+		})
+
+		Convey("When convert bigquery data into a string and raw message", func() {
+			res := []struct {
+				Name    string
+				Payload json.RawMessage
+			}{}
+			err := Convert(fields, rows, &res)
+			strRes := []struct {
+				Name    string
+				Payload string
+			}{}
+			strErr := Convert(fields, rows, &strRes)
+
+			Convey("Then the json is stored verbatim", func() {
+				So(err, ShouldBeNil)
+				So(string(res[0].Payload), ShouldEqual, `{"action":"click","tags":["a","b"],"meta":{"x":1}}`)
+				So(strErr, ShouldBeNil)
+				So(strRes[0].Payload, ShouldEqual, `{"action":"click","tags":["a","b"],"meta":{"x":1}}`)
+			})
+		})
+
+		Convey("When convert malformed json", func() {
+			malformed := []*bigquery.TableRow{
+				{F: []*bigquery.TableCell{{V: "test_name"}, {V: `{"action":`}}},
+			}
+			res := []struct {
+				Name    string
+				Payload payload
+			}{}
+			err := Convert(fields, malformed, &res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "Invalid JSON for payload")
+			})
+		})
+	})
+}
+
+func TestSetJobIDPrefix(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var sent bigquery.Job
+		c := newFakeJobClient(&sent)
+
+		Convey("When execute a query with a job ID prefix", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobIDPrefix("daily_report_").Execute(&res)
+
+			Convey("Then the query is inserted as a job with the prefixed ID", func() {
+				So(err, ShouldBeNil)
+				So(sent.JobReference, ShouldNotBeNil)
+				So(sent.JobReference.ProjectId, ShouldEqual, "winter_test00")
+				So(sent.JobReference.JobId, ShouldStartWith, "daily_report_")
+				So(len(sent.JobReference.JobId), ShouldBeGreaterThan, len("daily_report_"))
+			})
+		})
+
+		Convey("When execute queries with the same job ID prefix", func() {
+			var res []nameRec
+			So(c.Query("SELECT name FROM test").SetJobIDPrefix("daily_report_").Execute(&res), ShouldBeNil)
+			firstID := sent.JobReference.JobId
+			So(c.Query("SELECT name FROM test").SetJobIDPrefix("daily_report_").Execute(&res), ShouldBeNil)
+
+			Convey("Then each job has a unique ID", func() {
+				So(sent.JobReference.JobId, ShouldNotEqual, firstID)
+			})
+		})
+
+		Convey("When set an invalid job ID prefix", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetJobIDPrefix("daily report").Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `Invalid job ID prefix: "daily report"`)
+			})
+		})
+	})
+}
+
+func TestExecuteToTable(t *testing.T) {
+	Convey("Given a client whose service completes a query job", t, func() {
+		var sent bigquery.Job
+		var requests int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			requests++
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "DONE"}
+			}`), nil
+		}))
+
+		Convey("When execute a query into a table", func() {
+			err := c.Query("SELECT name FROM test").ExecuteToTable("summary", WriteTruncate, CreateIfNeeded)
+
+			Convey("Then the results are written into the table without fetching rows", func() {
+				So(err, ShouldBeNil)
+				So(requests, ShouldEqual, 1)
+
+				query := sent.Configuration.Query
+				So(query.DestinationTable, ShouldResemble, &bigquery.TableReference{ProjectId: "winter_test00", DatasetId: "bq_test", TableId: "summary"})
+				So(query.AllowLargeResults, ShouldBeTrue)
+				So(query.WriteDisposition, ShouldEqual, "WRITE_TRUNCATE")
+				So(query.CreateDisposition, ShouldEqual, "CREATE_IF_NEEDED")
+			})
+		})
+	})
+
+	Convey("Given a client whose service fails a query job", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {
+					"state": "DONE",
+					"errorResult": {"reason": "duplicate", "message": "Already Exists: Table winter_test00:bq_test.summary"}
+				}
+			}`), nil
+		}))
+
+		Convey("When execute a query into an existing table", func() {
+			err := c.Query("SELECT name FROM test").ExecuteToTable("summary", WriteEmpty, CreateIfNeeded)
+
+			Convey("Then a job error is returned", func() {
+				jobErr, ok := err.(*JobError)
+				So(ok, ShouldBeTrue)
+				So(jobErr.Reason, ShouldEqual, "duplicate")
+			})
+		})
+	})
+}
+
+func TestSkipMalformedRows(t *testing.T) {
+	Convey("Given a client whose service returns a row missing a cell", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobComplete": true,
+				"totalRows": "3",
+				"schema": {"fields": [
+					{"name": "name", "type": "STRING"},
+					{"name": "age", "type": "INTEGER"}
+				]},
+				"rows": [
+					{"f": [{"v": "test_name1"}, {"v": "26"}]},
+					{"f": [{"v": "test_name2"}]},
+					{"f": [{"v": "test_name3"}, {"v": "31"}]}
+				]
+			}`), nil
+		}))
+		type ageRec struct {
+			Name string
+			Age  int64
+		}
+
+		Convey("When execute a query", func() {
+			var res []ageRec
+			err := c.Query("SELECT name, age FROM test").Execute(&res)
+
+			Convey("Then the conversion is aborted at the malformed row", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid fields at row 1: 1 cells for 2 columns")
+			})
+		})
+
+		Convey("When execute a query skipping malformed rows", func() {
+			var res []ageRec
+			q := c.Query("SELECT name, age FROM test").SkipMalformedRows()
+			err := q.Execute(&res)
+
+			Convey("Then the malformed row is skipped and reported", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []ageRec{{Name: "test_name1", Age: 26}, {Name: "test_name3", Age: 31}})
+				So(q.SkippedRows(), ShouldResemble, []int{1})
+			})
+		})
+
+		Convey("When execute a query skipping malformed rows twice", func() {
+			var res []ageRec
+			q := c.Query("SELECT name, age FROM test").SkipMalformedRows()
+			So(q.Execute(&res), ShouldBeNil)
+			err := q.Execute(&res)
+
+			Convey("Then only rows skipped by the last execution are reported", func() {
+				So(err, ShouldBeNil)
+				So(q.SkippedRows(), ShouldResemble, []int{1})
+			})
+		})
+	})
+
+	Convey("Given a client whose service returns a row missing a cell on the second page", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("pageToken") == "" {
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": true,
+					"totalRows": "4",
+					"schema": {"fields": [
+						{"name": "name", "type": "STRING"},
+						{"name": "age", "type": "INTEGER"}
+					]},
+					"rows": [
+						{"f": [{"v": "test_name1"}, {"v": "26"}]},
+						{"f": [{"v": "test_name2"}, {"v": "28"}]}
+					],
+					"pageToken": "page_2"
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobComplete": true,
+				"totalRows": "4",
+				"schema": {"fields": [
+					{"name": "name", "type": "STRING"},
+					{"name": "age", "type": "INTEGER"}
+				]},
+				"rows": [
+					{"f": [{"v": "test_name3"}]},
+					{"f": [{"v": "test_name4"}, {"v": "31"}]}
+				]
+			}`), nil
+		}))
+		type ageRec struct {
+			Name string
+			Age  int64
+		}
+
+		Convey("When stream results skipping malformed rows", func() {
+			q := c.Query("SELECT name, age FROM test").SkipMalformedRows()
+			out := make(chan []ageRec)
+			errChan, err := q.ExecuteStream(out)
+			So(err, ShouldBeNil)
+
+			var res []ageRec
+			for page := range out {
+				res = append(res, page...)
+				q.SkippedRows()
+			}
+
+			Convey("Then the skipped row is reported by its index in all results", func() {
+				So(<-errChan, ShouldBeNil)
+				So(res, ShouldHaveLength, 3)
+				So(q.SkippedRows(), ShouldResemble, []int{2})
+			})
+		})
+	})
+}
+
+func TestConvertNonStringCells(t *testing.T) {
+	type addressRec struct {
+		Name    string
+		Address string
+	}
+
+	Convey("Given a record field", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "address", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+				{Mode: "NULLABLE", Name: "city", Type: "STRING"},
+			}},
+		}
+
+		Convey("When convert a map-valued cell into a scalar field", func() {
+			rows := []*bigquery.TableRow{
+				{F: []*bigquery.TableCell{{V: "test_name"}, {V: map[string]interface{}{"f": []interface{}{map[string]interface{}{"v": "tokyo"}}}}}},
+			}
+			res := []addressRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then a clear error is returned instead of a zero value", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column 'address' (RECORD) at index 1 has a record value which cannot map to field Address (string)")
+			})
+		})
+
+		Convey("When convert a slice-valued cell into a scalar field", func() {
+			rows := []*bigquery.TableRow{
+				{F: []*bigquery.TableCell{{V: "test_name"}, {V: []interface{}{}}}},
+			}
+			res := []addressRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then a clear error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "has a repeated value")
+			})
+		})
+
+		Convey("When convert a null cell", func() {
+			rows := []*bigquery.TableRow{
+				{F: []*bigquery.TableCell{{V: "test_name"}, {V: nil}}},
+			}
+			res := []addressRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then the field is left as a zero value", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []addressRec{{Name: "test_name"}})
+			})
+		})
+	})
+}
+
+func TestConvertRecord(t *testing.T) {
+	type cityRec struct {
+		Name    string
+		ZipCode int64
+	}
+	type addressRec struct {
+		City *cityRec
+		Tags []string `bq:"-"`
+		Line string
+	}
+	type userRec struct {
+		Name      string
+		Address   addressRec
+		Locations []cityRec
+	}
+
+	Convey("Given nested record fields and a repeated record field", t, func() {
+		cityFields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "zip_code", Type: "INTEGER"},
+		}
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "address", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+				{Mode: "NULLABLE", Name: "city", Type: "RECORD", Fields: cityFields},
+				{Mode: "NULLABLE", Name: "line", Type: "STRING"},
+			}},
+			{Mode: "REPEATED", Name: "locations", Type: "RECORD", Fields: cityFields},
+		}
+		city := func(name, zipCode string) map[string]interface{} {
+			return map[string]interface{}{"f": []interface{}{
+				map[string]interface{}{"v": name},
+				map[string]interface{}{"v": zipCode},
+			}}
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{
+				{V: "test_name"},
+				{V: map[string]interface{}{"f": []interface{}{
+					map[string]interface{}{"v": city("tokyo", "1000001")},
+					map[string]interface{}{"v": "1-1"},
+				}}},
+				{V: []interface{}{
+					map[string]interface{}{"v": city("osaka", "5300001")},
+					map[string]interface{}{"v": city("kyoto", "6000001")},
+				}},
+			}},
+			{F: []*bigquery.TableCell{
+				{V: "test_name2"},
+				{V: map[string]interface{}{"f": []interface{}{
+					map[string]interface{}{"v": nil},
+					map[string]interface{}{"v": "2-2"},
+				}}},
+				{V: []interface{}{}},
+			}},
+		}
+
+		Convey("When convert bigquery data into nested structs", func() {
+			res := []userRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then records are converted recursively", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []userRec{
+					{
+						Name:      "test_name",
+						Address:   addressRec{City: &cityRec{Name: "tokyo", ZipCode: 1000001}, Line: "1-1"},
+						Locations: []cityRec{{Name: "osaka", ZipCode: 5300001}, {Name: "kyoto", ZipCode: 6000001}},
+					},
+					{
+						Name:      "test_name2",
+						Address:   addressRec{Line: "2-2"},
+						Locations: []cityRec{},
+					},
+				})
+			})
+		})
+
+		Convey("When convert bigquery data with a record missing a nested cell", func() {
+			rows[0].F[1].V = map[string]interface{}{"f": []interface{}{
+				map[string]interface{}{"v": "1-1"},
+			}}
+			res := []userRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid record of address: 1 cells for 2 columns")
+			})
+		})
+	})
+}
+
+func TestConvertRepeated(t *testing.T) {
+	type arrayRec struct {
+		Tags    []string
+		Scores  []int64
+		Ratios  []float64
+		Flags   []bool
+		Visited []time.Time
+	}
+
+	Convey("Given repeated fields", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "REPEATED", Name: "tags", Type: "STRING"},
+			{Mode: "REPEATED", Name: "scores", Type: "INTEGER"},
+			{Mode: "REPEATED", Name: "ratios", Type: "FLOAT"},
+			{Mode: "REPEATED", Name: "flags", Type: "BOOLEAN"},
+			{Mode: "REPEATED", Name: "visited", Type: "TIMESTAMP"},
+		}
+		values := func(vs ...interface{}) []interface{} {
+			elems := make([]interface{}, len(vs))
+			for i, v := range vs {
+				elems[i] = map[string]interface{}{"v": v}
+			}
+			return elems
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{
+				{V: values("a", "b")},
+				{V: values("1", "2", "3")},
+				{V: values("0.5")},
+				{V: values("true", "false")},
+				{V: values("1.422943323E9")},
+			}},
+			{F: []*bigquery.TableCell{
+				{V: values()},
+				{V: values()},
+				{V: values()},
+				{V: values()},
+				{V: values()},
+			}},
+		}
+
+		Convey("When convert bigquery data into slices", func() {
+			res := []arrayRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then arrays are converted element by element", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldHaveLength, 2)
+				So(res[0].Tags, ShouldResemble, []string{"a", "b"})
+				So(res[0].Scores, ShouldResemble, []int64{1, 2, 3})
+				So(res[0].Ratios, ShouldResemble, []float64{0.5})
+				So(res[0].Flags, ShouldResemble, []bool{true, false})
+				So(res[0].Visited, ShouldHaveLength, 1)
+				So(res[0].Visited[0].Unix(), ShouldEqual, 1422943323)
+				So(res[1].Tags, ShouldBeEmpty)
+				So(res[1].Scores, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When convert bigquery data into a slice of a mismatching type", func() {
+			type mismatchRec struct {
+				Tags    []int64
+				Scores  []int64
+				Ratios  []float64
+				Flags   []bool
+				Visited []time.Time
+			}
+			res := []mismatchRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column 'tags' (STRING) at index 0 has a repeated value which cannot map to field Tags (slice)")
+			})
+		})
+	})
+}
+
+func TestConvertTimestampString(t *testing.T) {
+	type timeRec struct {
+		CreatedAt string
+	}
+
+	Convey("Given a timestamp field", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "created_at", Type: "TIMESTAMP"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "1.422943323461E9"}}},
+			{F: []*bigquery.TableCell{{V: "1.422943323E9"}}},
+		}
+
+		Convey("When convert bigquery data into a string field", func() {
+			res := []timeRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then timestamps are formatted as RFC3339 in UTC", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []timeRec{
+					{CreatedAt: "2015-02-03T06:02:03.461Z"},
+					{CreatedAt: "2015-02-03T06:02:03Z"},
+				})
+			})
+		})
+	})
+}
+
+func TestConvertTimestampPrecision(t *testing.T) {
+	type timeRec struct {
+		Micros    int64
+		CreatedAt time.Time
+	}
+
+	Convey("Given timestamp fields with microsecond precision", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "micros", Type: "TIMESTAMP"},
+			{Mode: "NULLABLE", Name: "created_at", Type: "TIMESTAMP"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "1.422943323461123E9"}, {V: "1.422943323461123E9"}}},
+			{F: []*bigquery.TableCell{{V: "1.4229433230000011E9"}, {V: "1422943323.000001"}}},
+			{F: []*bigquery.TableCell{{V: "-1.5E0"}, {V: "1.0E-6"}}},
+			{F: []*bigquery.TableCell{{V: "1422943323"}, {V: "0"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []timeRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then timestamps are converted without rounding drift", func() {
+				So(err, ShouldBeNil)
+				So(res[0].Micros, ShouldEqual, 1422943323461123)
+				So(res[0].CreatedAt, ShouldResemble, time.Date(2015, 2, 3, 6, 2, 3, 461123000, time.UTC))
+				So(res[1].Micros, ShouldEqual, 1422943323000001)
+				So(res[1].CreatedAt, ShouldResemble, time.Date(2015, 2, 3, 6, 2, 3, 1000, time.UTC))
+				So(res[2].Micros, ShouldEqual, -1500000)
+				So(res[2].CreatedAt, ShouldResemble, time.Date(1970, 1, 1, 0, 0, 0, 1000, time.UTC))
+				So(res[3].Micros, ShouldEqual, 1422943323000000)
+				So(res[3].CreatedAt, ShouldResemble, time.Unix(0, 0).UTC())
+			})
+		})
+
+		Convey("When convert a malformed timestamp", func() {
+			malformed := []*bigquery.TableRow{
+				{F: []*bigquery.TableCell{{V: "1.42E9x"}, {V: "0"}}},
+			}
+			res := []timeRec{}
+			err := Convert(fields, malformed, &res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid timestamp format")
 			})
 		})
 	})
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// Converter converts a raw cell value into a struct field before the built-in conversion
+// ConvertCell returns false to leave the cell to other converters and the built-in conversion
+type Converter interface {
+	ConvertCell(fieldType string, raw string, dst reflect.Value) (handled bool, err error)
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   []Converter
+)
+
+// RegisterConverter registers a converter consulted by Convert and all clients
+func RegisterConverter(conv Converter) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters = append(converters, conv)
+}
+
+// RegisterConverter registers a converter consulted when converting results of the client
+// Converters of the client are consulted before ones registered with the package-level RegisterConverter
+func (c *Client) RegisterConverter(conv Converter) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.converters = append(c.converters, conv)
+	return c
+}
+
+// FieldMatcher reports whether a column matches a field of a result struct by their names
+type FieldMatcher func(column, goField string) bool
+
+// SnakeCaseMatcher matches a snake_case column like user_name with a field like UserName
+// Underscores in a column name are ignored and names are compared case-insensitively
+func SnakeCaseMatcher(column, goField string) bool {
+	return strings.EqualFold(strings.Replace(column, "_", "", -1), goField)
+}
+
+// SetFieldMatcher sets a matcher to map columns into fields of a result struct by their names
+// Columns are mapped into fields by their positions without a matcher unless the struct has bq tags
+func (c *Client) SetFieldMatcher(matcher FieldMatcher) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.matcher = matcher
+	return c
+}
+
+// convertOptions controls how Convert maps cells into struct fields
+type convertOptions struct {
+	converters    []Converter
+	matcher       FieldMatcher
+	lenient       bool
+	ignoreUnknown bool
+	skipMalformed bool
+
+	// skipped is indexes of rows skipped by skipMalformed
+	skipped []int
+}
+
+// convertOptions returns options to convert results of the client
+func (c *Client) convertOptions() *convertOptions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &convertOptions{
+		converters: append([]Converter(nil), c.converters...),
+		matcher:    c.matcher,
+	}
+}
+
+// fieldIndexes returns an index of a struct field to map each column into
+// Columns are mapped by their positions unless the struct has bq tags, a matcher is set or unknown columns are ignored,
+// and the index is -1 for an ignored column
+func (o *convertOptions) fieldIndexes(fields []*bigquery.TableFieldSchema, elemT reflect.Type) ([]int, error) {
+	indexes := make([]int, len(fields))
+	matcher := o.matcher
+	if matcher == nil && (o.ignoreUnknown || hasColumnTags(elemT)) {
+		matcher = strings.EqualFold
+	}
+	if matcher == nil {
+		if elemT.NumField() != len(fields) {
+			return nil, newFieldCountError(fields, elemT)
+		}
+		for j := range indexes {
+			indexes[j] = j
+		}
+		return indexes, nil
+	}
+
+	for j, field := range fields {
+		indexes[j] = columnFieldIndex(field.Name, elemT, matcher)
+		if indexes[j] < 0 && !o.ignoreUnknown {
+			return nil, &ConvertError{Column: field.Name, Index: j, Type: field.Type}
+		}
+	}
+	return indexes, nil
+}
+
+// hasColumnTags reports whether a struct has a field with a bq tag to map a column by name
+func hasColumnTags(elemT reflect.Type) bool {
+	for i := 0; i < elemT.NumField(); i++ {
+		if _, ok := elemT.Field(i).Tag.Lookup("bq"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// columnFieldIndex returns an index of a struct field to map a given column into or -1 if no field matches
+// A field with a bq tag like `bq:"user_name"` matches by the tag before other fields are compared by the matcher,
+// and a field tagged with `bq:"-"` is never mapped
+func columnFieldIndex(column string, elemT reflect.Type, matcher FieldMatcher) int {
+	for i := 0; i < elemT.NumField(); i++ {
+		f := elemT.Field(i)
+		if tag := columnTag(f); f.PkgPath == "" && tag != "-" && strings.EqualFold(tag, column) {
+			return i
+		}
+	}
+	for i := 0; i < elemT.NumField(); i++ {
+		f := elemT.Field(i)
+		if f.PkgPath == "" && columnTag(f) == "" && matcher(column, f.Name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnTag returns a column name in the bq tag of a struct field like `bq:"user_name,required"`
+func columnTag(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("bq"), ",")[0]
+}
+
+// convert converts bigquery data into a given result with converters of the client
+func (c *Client) convert(fields []*bigquery.TableFieldSchema, rows []*bigquery.TableRow, result interface{}) error {
+	return convert(fields, rows, result, c.convertOptions())
+}
+
+// convertCell tries registered converters in order and reports whether one of them handled the cell
+func (o *convertOptions) convertCell(fieldType string, raw string, dst reflect.Value) (bool, error) {
+	convertersMu.RLock()
+	all := append(append([]Converter(nil), o.converters...), converters...)
+	convertersMu.RUnlock()
+
+	for _, conv := range all {
+		handled, err := conv.ConvertCell(fieldType, raw, dst)
+		if err != nil {
+			return false, err
+		}
+		if handled {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// convertNested converts a RECORD cell into a struct field and a REPEATED cell into a slice
+// It reports whether the cell was converted, so that the caller reports a cell not matching the field
+func (o *convertOptions) convertNested(field *bigquery.TableFieldSchema, value interface{}, dst reflect.Value) (bool, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if field.Type != fieldTypeRecord || !isStructValue(dst.Type()) {
+			return false, nil
+		}
+		return true, o.convertRecord(field, v, dst)
+	case []interface{}:
+		if dst.Kind() != reflect.Slice || dst.Type() == rawMessageType {
+			return false, nil
+		}
+		sliceV := reflect.MakeSlice(dst.Type(), len(v), len(v))
+		for i, item := range v {
+			// an element of a REPEATED cell is wrapped like {"v": ...}
+			elem, _ := item.(map[string]interface{})
+			converted, err := o.convertElement(field, elem["v"], sliceV.Index(i))
+			if err != nil || !converted {
+				return converted, err
+			}
+		}
+		dst.Set(sliceV)
+		return true, nil
+	}
+	return false, nil
+}
+
+// convertElement converts an element of a REPEATED cell into an element of a slice
+func (o *convertOptions) convertElement(field *bigquery.TableFieldSchema, value interface{}, dst reflect.Value) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return true, nil
+	case map[string]interface{}:
+		if field.Type != fieldTypeRecord || !isStructValue(dst.Type()) {
+			return false, nil
+		}
+		return true, o.convertRecord(field, v, dst)
+	case string:
+		handled, err := o.convertCell(field.Type, v, dst)
+		if err != nil || handled {
+			return handled, err
+		}
+		return o.convertValue(field, v, dst)
+	}
+	return false, nil
+}
+
+// convertRecord converts a RECORD value like {"f": [{"v": ...}]} into a struct or a pointer to a struct
+// Nested columns are mapped into fields in the same way as top level columns
+func (o *convertOptions) convertRecord(field *bigquery.TableFieldSchema, record map[string]interface{}, dst reflect.Value) error {
+	values, _ := record["f"].([]interface{})
+	cells := make([]*bigquery.TableCell, len(values))
+	for i, value := range values {
+		cell, _ := value.(map[string]interface{})
+		cells[i] = &bigquery.TableCell{V: cell["v"]}
+	}
+	if len(cells) != len(field.Fields) {
+		return fmt.Errorf("Invalid record of %s: %d cells for %d columns", field.Name, len(cells), len(field.Fields))
+	}
+
+	if dst.Kind() == reflect.Ptr {
+		dst.Set(reflect.New(dst.Type().Elem()))
+		dst = dst.Elem()
+	}
+	indexes, err := o.fieldIndexes(field.Fields, dst.Type())
+	if err != nil {
+		return err
+	}
+	return o.convertRow(field.Fields, cells, dst, indexes)
+}
+
+// isStructValue reports whether a RECORD can be converted into a given type of a struct or a pointer to a struct
+func isStructValue(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
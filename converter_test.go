@@ -0,0 +1,218 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+type attributes struct {
+	Color string `json:"color"`
+	Size  int    `json:"size"`
+}
+
+type attributesRec struct {
+	Name       string
+	Attributes attributes
+}
+
+// jsonConverter decodes JSON encoded STRING cells into attributes fields
+type jsonConverter struct{}
+
+func (jsonConverter) ConvertCell(fieldType string, raw string, dst reflect.Value) (bool, error) {
+	if fieldType != fieldTypeString || dst.Type() != reflect.TypeOf(attributes{}) {
+		return false, nil
+	}
+	return true, json.Unmarshal([]byte(raw), dst.Addr().Interface())
+}
+
+func TestRegisterConverter(t *testing.T) {
+	Convey("Given a string field holding JSON", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "attributes", Type: "STRING"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "test_name"}, {V: `{"color": "red", "size": 3}`}}},
+		}
+
+		Convey("When convert without a converter", func() {
+			res := []attributesRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When execute a query with a converter registered with the client", func() {
+			c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+				return jsonResponse(http.StatusOK, `{
+					"jobComplete": true,
+					"totalRows": "1",
+					"schema": {"fields": [
+						{"name": "name", "type": "STRING"},
+						{"name": "attributes", "type": "STRING"}
+					]},
+					"rows": [{"f": [{"v": "test_name"}, {"v": "{\"color\": \"red\", \"size\": 3}"}]}]
+				}`), nil
+			}))
+			c.RegisterConverter(jsonConverter{})
+
+			res := []attributesRec{}
+			err := c.Query("SELECT name, attributes FROM test").Execute(&res)
+
+			Convey("Then the column is decoded by the converter", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []attributesRec{
+					{Name: "test_name", Attributes: attributes{Color: "red", Size: 3}},
+				})
+			})
+		})
+
+		Convey("When convert with a converter registered globally", func() {
+			RegisterConverter(jsonConverter{})
+			defer func() {
+				convertersMu.Lock()
+				converters = nil
+				convertersMu.Unlock()
+			}()
+
+			res := []attributesRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then the column is decoded by the converter", func() {
+				So(err, ShouldBeNil)
+				So(res[0].Attributes, ShouldResemble, attributes{Color: "red", Size: 3})
+			})
+		})
+	})
+}
+
+func TestSetFieldMatcher(t *testing.T) {
+	type userRec struct {
+		UserID   int64
+		UserName string
+	}
+
+	Convey("Given a client whose service returns snake_case columns in a different order", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [
+					{"name": "user_name", "type": "STRING"},
+					{"name": "user_id", "type": "INTEGER"}
+				]},
+				"rows": [{"f": [{"v": "test_name"}, {"v": "26"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query with the snake case matcher", func() {
+			c.SetFieldMatcher(SnakeCaseMatcher)
+
+			res := []userRec{}
+			err := c.Query("SELECT user_name, user_id FROM test").Execute(&res)
+
+			Convey("Then columns are mapped into fields by names", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []userRec{{UserID: 26, UserName: "test_name"}})
+			})
+		})
+
+		Convey("When execute a query with a matcher not matching a column", func() {
+			c.SetFieldMatcher(func(column, goField string) bool {
+				return column == goField
+			})
+
+			res := []userRec{}
+			err := c.Query("SELECT user_name, user_id FROM test").Execute(&res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column 'user_name' (STRING) at index 0 has no field to map")
+			})
+		})
+
+		Convey("When execute a query without a matcher", func() {
+			res := []userRec{}
+			err := c.Query("SELECT user_name, user_id FROM test").Execute(&res)
+
+			Convey("Then columns are mapped into fields by positions", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column 'user_name' (STRING) at index 0 cannot map to field UserID (int64)")
+			})
+		})
+	})
+}
+
+func TestConvertByTags(t *testing.T) {
+	type userRec struct {
+		ID       int64  `bq:"user_id"`
+		UserName string `bq:"name,required"`
+		Age      int64
+		Ignored  string `bq:"-"`
+	}
+
+	Convey("Given columns in a different order from fields of a tagged struct", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "AGE", Type: "INTEGER"},
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "user_id", Type: "INTEGER"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "26"}, {V: "test_name"}, {V: "1"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []userRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then columns are mapped by tags and case-insensitive field names", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []userRec{{ID: 1, UserName: "test_name", Age: 26}})
+			})
+		})
+
+		Convey("When convert bigquery data with a column only matching an ignored field", func() {
+			ignored := append(fields, &bigquery.TableFieldSchema{Mode: "NULLABLE", Name: "ignored", Type: "STRING"})
+			ignoredRows := []*bigquery.TableRow{
+				{F: []*bigquery.TableCell{{V: "26"}, {V: "test_name"}, {V: "1"}, {V: "ignored"}}},
+			}
+			res := []userRec{}
+			err := Convert(ignored, ignoredRows, &res)
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column 'ignored' (STRING) at index 3 has no field to map")
+			})
+		})
+	})
+
+	Convey("Given a tagged field and an untagged field with the same column name", t, func() {
+		type nameRec struct {
+			Name     string
+			FullName string `bq:"name"`
+		}
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "test_name"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []nameRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then the tagged field takes precedence", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{FullName: "test_name"}})
+			})
+		})
+	})
+}
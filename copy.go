@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// TableRef is a reference to a table
+// ProjectID and DatasetID default to the dataset of the client if empty
+type TableRef struct {
+	ProjectID string
+	DatasetID string
+	TableID   string
+}
+
+// tableReference resolves the reference with the dataset of a given client
+// An error is returned if the reference needs the dataset of the client which is not set
+func (r TableRef) tableReference(c *Client) (*bigquery.TableReference, error) {
+	ref := &bigquery.TableReference{
+		ProjectId: r.ProjectID,
+		DatasetId: r.DatasetID,
+		TableId:   r.TableID,
+	}
+	if ref.ProjectId != "" && ref.DatasetId != "" {
+		return ref, nil
+	}
+
+	dataset, err := c.dataset()
+	if err != nil {
+		return nil, err
+	}
+	if ref.ProjectId == "" {
+		ref.ProjectId = dataset.ProjectId
+	}
+	if ref.DatasetId == "" {
+		ref.DatasetId = dataset.DatasetId
+	}
+	return ref, nil
+}
+
+// CopyTable copies a table in the dataset to a given destination and waits for its completion
+// A JobError is returned if the copy job fails
+func (c *Client) CopyTable(srcTableID string, dst TableRef, writeDisp WriteDisp) error {
+	srcRef, err := TableRef{TableID: srcTableID}.tableReference(c)
+	if err != nil {
+		return err
+	}
+	dstRef, err := dst.tableReference(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.runJob(context.Background(), &bigquery.Job{
+		Configuration: &bigquery.JobConfiguration{
+			Copy: &bigquery.JobConfigurationTableCopy{
+				SourceTable:      srcRef,
+				DestinationTable: dstRef,
+				WriteDisposition: string(writeDisp),
+			},
+		},
+	})
+	return err
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestCopyTable(t *testing.T) {
+	Convey("Given a client whose service runs a copy job", t, func() {
+		var sent bigquery.Job
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "DONE"}
+			}`), nil
+		}))
+
+		Convey("When copy a table within the dataset", func() {
+			err := c.CopyTable("test_table", TableRef{TableID: "test_table_copy"}, WriteTruncate)
+
+			Convey("Then the copy job is run in the same dataset", func() {
+				So(err, ShouldBeNil)
+
+				tableCopy := sent.Configuration.Copy
+				So(tableCopy.SourceTable, ShouldResemble, &bigquery.TableReference{ProjectId: "winter_test00", DatasetId: "bq_test", TableId: "test_table"})
+				So(tableCopy.DestinationTable, ShouldResemble, &bigquery.TableReference{ProjectId: "winter_test00", DatasetId: "bq_test", TableId: "test_table_copy"})
+				So(tableCopy.WriteDisposition, ShouldEqual, "WRITE_TRUNCATE")
+			})
+		})
+
+		Convey("When copy a table to another dataset", func() {
+			err := c.CopyTable("test_table", TableRef{ProjectID: "winter_backup", DatasetID: "bq_snapshot", TableID: "test_table_20150203"}, WriteEmpty)
+
+			Convey("Then the copy job is run across datasets", func() {
+				So(err, ShouldBeNil)
+
+				tableCopy := sent.Configuration.Copy
+				So(tableCopy.SourceTable, ShouldResemble, &bigquery.TableReference{ProjectId: "winter_test00", DatasetId: "bq_test", TableId: "test_table"})
+				So(tableCopy.DestinationTable, ShouldResemble, &bigquery.TableReference{ProjectId: "winter_backup", DatasetId: "bq_snapshot", TableId: "test_table_20150203"})
+				So(tableCopy.WriteDisposition, ShouldEqual, "WRITE_EMPTY")
+			})
+		})
+
+		Convey("When copy a table without a dataset", func() {
+			c.datasetRef = nil
+			err := c.CopyTable("test_table", TableRef{ProjectID: "winter_backup", DatasetID: "bq_snapshot", TableID: "test_table_20150203"}, WriteEmpty)
+
+			Convey("Then err is returned for the source table", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "No dataset")
+			})
+		})
+	})
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// ExecuteCSV executes a given query and writes results into a given writer as CSV
+// A header row of column names is written first and rows are flushed page by page
+// NULL cells are written as empty fields and TIMESTAMP cells are formatted in UTC
+func (q *Query) ExecuteCSV(w io.Writer) error {
+	p, err := q.newPager()
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	var headerWritten bool
+	for {
+		rows, err := p.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !headerWritten && len(p.fields) > 0 {
+			header := make([]string, 0, len(p.fields))
+			for _, field := range p.fields {
+				header = append(header, field.Name)
+			}
+			if err := cw.Write(header); err != nil {
+				return err
+			}
+			headerWritten = true
+		}
+
+		for _, row := range rows {
+			record, err := csvRecord(p.fields, row)
+			if err != nil {
+				return err
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRecord formats cells of a given row by their field types
+func csvRecord(fields []*bigquery.TableFieldSchema, row *bigquery.TableRow) ([]string, error) {
+	if len(fields) != len(row.F) {
+		return nil, errors.New("Invalid fields")
+	}
+
+	record := make([]string, 0, len(row.F))
+	for i, cell := range row.F {
+		switch v := cell.V.(type) {
+		case nil:
+			record = append(record, "")
+		case string:
+			if fields[i].Type != fieldTypeTimestamp {
+				record = append(record, v)
+				continue
+			}
+			us, err := parseTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			record = append(record, timeFromMicros(us).Format(timestampFormat))
+		default:
+			// RECORD and REPEATED cells are written as JSON
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid cell for %s: %v", fields[i].Name, err)
+			}
+			record = append(record, string(b))
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExecuteCSV(t *testing.T) {
+	Convey("Given a client whose service returns results in two pages", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"jobComplete": true,
+					"totalRows": "2",
+					"schema": {"fields": [
+						{"name": "name", "type": "STRING"},
+						{"name": "age", "type": "INTEGER"},
+						{"name": "timestamp", "type": "TIMESTAMP"}
+					]},
+					"rows": [{"f": [{"v": "test, name"}, {"v": "26"}, {"v": "1.422943323461E9"}]}],
+					"pageToken": "page_2"
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "2",
+				"schema": {"fields": [
+					{"name": "name", "type": "STRING"},
+					{"name": "age", "type": "INTEGER"},
+					{"name": "timestamp", "type": "TIMESTAMP"}
+				]},
+				"rows": [{"f": [{"v": "test_name"}, {"v": null}, {"v": null}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query as CSV", func() {
+			var buf bytes.Buffer
+			err := c.Query("SELECT name, age, timestamp FROM test").ExecuteCSV(&buf)
+
+			Convey("Then a header and all rows are written", func() {
+				So(err, ShouldBeNil)
+				So(buf.String(), ShouldEqual, "name,age,timestamp\n"+
+					"\"test, name\",26,2015-02-03 06:02:03.461 UTC\n"+
+					"test_name,,\n")
+			})
+		})
+	})
+}
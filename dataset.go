@@ -0,0 +1,56 @@
+package client
+
+import (
+	"errors"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// dataset returns the dataset set by Dataset or an error if it is not set
+func (c *Client) dataset() (*bigquery.DatasetReference, error) {
+	if c.datasetRef == nil {
+		return nil, errors.New("No dataset")
+	}
+	return c.datasetRef, nil
+}
+
+// CreateDataset creates the dataset set by Dataset in a given location
+func (c *Client) CreateDataset(location string) error {
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return err
+	}
+
+	dataset := &bigquery.Dataset{
+		DatasetReference: ref,
+		Location:         location,
+	}
+
+	return c.do(func() error {
+		_, err := service.Datasets.Insert(ref.ProjectId, dataset).Do()
+		return err
+	})
+}
+
+// DeleteDataset deletes the dataset set by Dataset
+// Tables in the dataset are also deleted if deleteContents is true
+func (c *Client) DeleteDataset(deleteContents bool) error {
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return err
+	}
+
+	return c.do(func() error {
+		return service.Datasets.Delete(ref.ProjectId, ref.DatasetId).DeleteContents(deleteContents).Do()
+	})
+}
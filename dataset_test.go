@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestCreateDataset(t *testing.T) {
+	Convey("Given a client whose service creates a dataset", t, func() {
+		var sent bigquery.Dataset
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test"}`), nil
+		}))
+
+		Convey("When create a dataset", func() {
+			err := c.CreateDataset("asia-northeast1")
+
+			Convey("Then the dataset is created in the location", func() {
+				So(err, ShouldBeNil)
+				So(sent.DatasetReference.ProjectId, ShouldEqual, "winter_test00")
+				So(sent.DatasetReference.DatasetId, ShouldEqual, "bq_test")
+				So(sent.Location, ShouldEqual, "asia-northeast1")
+			})
+		})
+
+		Convey("When create a dataset without dataset reference", func() {
+			c.datasetRef = nil
+			err := c.CreateDataset("asia-northeast1")
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "No dataset")
+			})
+		})
+	})
+}
+
+func TestDeleteDataset(t *testing.T) {
+	Convey("Given a client whose service deletes a dataset", t, func() {
+		var path, deleteContents string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			path = req.URL.Path
+			deleteContents = req.URL.Query().Get("deleteContents")
+			return jsonResponse(http.StatusNoContent, ``), nil
+		}))
+
+		Convey("When delete a dataset with contents", func() {
+			err := c.DeleteDataset(true)
+
+			Convey("Then the dataset is deleted with contents", func() {
+				So(err, ShouldBeNil)
+				So(path, ShouldEqual, "/bigquery/v2/projects/winter_test00/datasets/bq_test")
+				So(deleteContents, ShouldEqual, "true")
+			})
+		})
+
+		Convey("When delete a dataset without dataset reference", func() {
+			c.datasetRef = nil
+			err := c.DeleteDataset(false)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "No dataset")
+			})
+		})
+	})
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	reasonRateLimitExceeded = "rateLimitExceeded"
+	reasonQuotaExceeded     = "quotaExceeded"
+	reasonInvalidQuery      = "invalidQuery"
+	reasonInvalid           = "invalid"
+	reasonAccessDenied      = "accessDenied"
+)
+
+// ErrQueryTimeout is returned when a query doesn't complete within the timeout set by Query.SetTimeout
+var ErrQueryTimeout = errors.New("Query timed out")
+
+// APIError is an error response from bigquery API
+// Reasons are taken from details of the response like notFound or rateLimitExceeded
+type APIError struct {
+	Code    int
+	Message string
+	Reasons []string
+	err     *googleapi.Error
+}
+
+// newAPIError wraps a given error into an APIError if it is an error response from bigquery API
+func newAPIError(err error) error {
+	apiErr, ok := err.(*googleapi.Error)
+	if !ok {
+		return err
+	}
+
+	reasons := make([]string, 0, len(apiErr.Errors))
+	for _, item := range apiErr.Errors {
+		reasons = append(reasons, item.Reason)
+	}
+	return &APIError{
+		Code:    apiErr.Code,
+		Message: apiErr.Message,
+		Reasons: reasons,
+		err:     apiErr,
+	}
+}
+
+// Error returns the message of the original googleapi error
+func (e *APIError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the original googleapi error
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// IsUnauthenticated reports whether a request is rejected by invalid or expired credentials
+func (e *APIError) IsUnauthenticated() bool {
+	return e.Code == http.StatusUnauthorized
+}
+
+// IsNotFound reports whether a requested resource like a table doesn't exist
+func (e *APIError) IsNotFound() bool {
+	return e.Code == http.StatusNotFound
+}
+
+// IsPermissionDenied reports whether a request is denied by lack of permission
+// 403 responses for exceeded rate limits or quotas are not regarded as permission denied
+func (e *APIError) IsPermissionDenied() bool {
+	return e.Code == http.StatusForbidden && !e.IsRateLimited()
+}
+
+// IsRateLimited reports whether a request is rejected by rate limits or quotas
+func (e *APIError) IsRateLimited() bool {
+	if e.Code == http.StatusTooManyRequests {
+		return true
+	}
+	return e.hasReason(reasonRateLimitExceeded) || e.hasReason(reasonQuotaExceeded)
+}
+
+func (e *APIError) hasReason(reason string) bool {
+	for _, r := range e.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// JobError is an error reported by a failed job
+type JobError struct {
+	Reason   string
+	Location string
+	Message  string
+	Errors   []*bigquery.ErrorProto
+}
+
+// newJobError generates a JobError from a given job status if the job failed
+func newJobError(status *bigquery.JobStatus) error {
+	if status == nil || status.ErrorResult == nil {
+		return nil
+	}
+	return &JobError{
+		Reason:   status.ErrorResult.Reason,
+		Location: status.ErrorResult.Location,
+		Message:  status.ErrorResult.Message,
+		Errors:   status.Errors,
+	}
+}
+
+// Error describes the reason and message of the failure
+func (e *JobError) Error() string {
+	return fmt.Sprintf("Job failed: %s: %s", e.Reason, e.Message)
+}
+
+// IsRateLimited reports whether the job failed by rate limits or quotas
+func (e *JobError) IsRateLimited() bool {
+	return e.hasReason(reasonRateLimitExceeded) || e.hasReason(reasonQuotaExceeded)
+}
+
+// IsInvalidQuery reports whether the job failed by an invalid query like a syntax error or an unknown column
+func (e *JobError) IsInvalidQuery() bool {
+	return e.hasReason(reasonInvalidQuery)
+}
+
+// IsInvalid reports whether the job failed by invalid data or configuration like a schema mismatch
+func (e *JobError) IsInvalid() bool {
+	return e.hasReason(reasonInvalid)
+}
+
+// IsAccessDenied reports whether the job failed by lack of permission to a resource like a table
+func (e *JobError) IsAccessDenied() bool {
+	return e.hasReason(reasonAccessDenied)
+}
+
+// hasReason reports whether the error result or one of the errors of the job has a given reason
+func (e *JobError) hasReason(reason string) bool {
+	if e.Reason == reason {
+		return true
+	}
+	for _, item := range e.Errors {
+		if item.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyError is an error for a private key which cannot be parsed
+type KeyError struct {
+	Err error
+}
+
+// Error describes why the key is invalid
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("Invalid private key: %v", e.Err)
+}
+
+// InsertError is an error for rows failed in a streaming insert
+type InsertError struct {
+	rowErrors []*bigquery.TableDataInsertAllResponseInsertErrors
+}
+
+// Error summarizes failed rows
+func (e *InsertError) Error() string {
+	msg := fmt.Sprintf("Failed to insert %d rows", len(e.rowErrors))
+	if len(e.rowErrors) > 0 && len(e.rowErrors[0].Errors) > 0 {
+		first := e.rowErrors[0]
+		msg += fmt.Sprintf(": row %d: %s", first.Index, first.Errors[0].Message)
+	}
+	return msg
+}
+
+// RowErrors returns errors with the index of each failed row
+func (e *InsertError) RowErrors() []*bigquery.TableDataInsertAllResponseInsertErrors {
+	return e.rowErrors
+}
+
+// FailedRows returns indexes of failed rows
+// Rows rejected only because other rows in the same request failed have the reason stopped
+func (e *InsertError) FailedRows() []int64 {
+	indexes := make([]int64, 0, len(e.rowErrors))
+	for _, rowErr := range e.rowErrors {
+		indexes = append(indexes, rowErr.Index)
+	}
+	return indexes
+}
+
+// Reasons returns distinct reasons of errors of failed rows like invalid or stopped
+func (e *InsertError) Reasons() []string {
+	var reasons []string
+	seen := map[string]bool{}
+	for _, rowErr := range e.rowErrors {
+		for _, item := range rowErr.Errors {
+			if !seen[item.Reason] {
+				seen[item.Reason] = true
+				reasons = append(reasons, item.Reason)
+			}
+		}
+	}
+	return reasons
+}
+
+// ConvertError is an error for a column which cannot be mapped into a field of a result struct
+// Column and Type are empty if the struct has a field without a corresponding column,
+// and Field is empty if a column has no corresponding field
+type ConvertError struct {
+	Column string
+	Index  int
+	Type   string
+	Field  string
+	Kind   reflect.Kind
+}
+
+// newFieldCountError generates a ConvertError for the first column or field without a counterpart
+func newFieldCountError(fields []*bigquery.TableFieldSchema, elemT reflect.Type) error {
+	i := len(fields)
+	if elemT.NumField() > i {
+		f := elemT.Field(i)
+		return &ConvertError{Index: i, Field: f.Name, Kind: f.Type.Kind()}
+	}
+	i = elemT.NumField()
+	return &ConvertError{Column: fields[i].Name, Index: i, Type: fields[i].Type}
+}
+
+// Error describes the mismatching column and field
+func (e *ConvertError) Error() string {
+	switch {
+	case e.Field == "":
+		return fmt.Sprintf("Column '%s' (%s) at index %d has no field to map", e.Column, e.Type, e.Index)
+	case e.Column == "":
+		return fmt.Sprintf("Field %s (%s) at index %d has no column to map", e.Field, e.Kind, e.Index)
+	}
+	return fmt.Sprintf("Column '%s' (%s) at index %d cannot map to field %s (%s)", e.Column, e.Type, e.Index, e.Field, e.Kind)
+}
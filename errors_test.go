@@ -0,0 +1,228 @@
+package client
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestInsertError(t *testing.T) {
+	Convey("Given a client whose service rejects a row", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"insertErrors": [
+					{"index": 1, "errors": [{"reason": "invalid", "location": "age", "message": "Cannot convert value to integer"}]}
+				]
+			}`), nil
+		}))
+
+		Convey("When insert rows", func() {
+			err := c.InsertRowsByJSON("test_table", []map[string]interface{}{
+				{"age": 26},
+				{"age": "unknown"},
+			})
+
+			Convey("Then an insert error with row details is returned", func() {
+				So(err, ShouldNotBeNil)
+				insertErr, ok := err.(*InsertError)
+				So(ok, ShouldBeTrue)
+				So(insertErr.Error(), ShouldEqual, "Failed to insert 1 rows: row 1: Cannot convert value to integer")
+
+				rowErrors := insertErr.RowErrors()
+				So(len(rowErrors), ShouldEqual, 1)
+				So(rowErrors[0].Index, ShouldEqual, 1)
+				So(rowErrors[0].Errors[0].Reason, ShouldEqual, "invalid")
+				So(rowErrors[0].Errors[0].Message, ShouldEqual, "Cannot convert value to integer")
+				So(insertErr.FailedRows(), ShouldResemble, []int64{1})
+				So(insertErr.Reasons(), ShouldResemble, []string{"invalid"})
+			})
+		})
+	})
+}
+
+func TestConvertError(t *testing.T) {
+	type ageRec struct {
+		Name string
+		Age  int
+	}
+
+	Convey("Given rows with a column mismatching a struct field", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "score", Type: "FLOAT"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "test_name"}, {V: "12.5"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []ageRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then a convert error naming the column and field is returned", func() {
+				So(err, ShouldNotBeNil)
+				convertErr, ok := err.(*ConvertError)
+				So(ok, ShouldBeTrue)
+				So(convertErr.Column, ShouldEqual, "score")
+				So(convertErr.Index, ShouldEqual, 1)
+				So(convertErr.Type, ShouldEqual, "FLOAT")
+				So(convertErr.Field, ShouldEqual, "Age")
+				So(convertErr.Kind, ShouldEqual, reflect.Int)
+				So(err.Error(), ShouldEqual, "Column 'score' (FLOAT) at index 1 cannot map to field Age (int)")
+			})
+		})
+	})
+
+	Convey("Given rows with more columns than struct fields", t, func() {
+		type nameOnlyRec struct {
+			Name string
+		}
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "name", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "score", Type: "FLOAT"},
+		}
+		rows := []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "test_name"}, {V: "12.5"}}},
+		}
+
+		Convey("When convert bigquery data", func() {
+			res := []nameOnlyRec{}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then a convert error naming the extra column is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "score")
+				So(err.Error(), ShouldEqual, "Column 'score' (FLOAT) at index 1 has no field to map")
+			})
+		})
+	})
+}
+
+func TestJobErrorOfQuery(t *testing.T) {
+	Convey("Given a client whose service rejects a query job on insert", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {
+					"state": "DONE",
+					"errorResult": {"reason": "invalidQuery", "location": "query", "message": "Unrecognized name: nme"}
+				}
+			}`), nil
+		}))
+
+		Convey("When execute a query as a job", func() {
+			var res []nameRec
+			err := c.Query("SELECT nme FROM test").SetPriority(PriorityBatch).Execute(&res)
+
+			Convey("Then a job error with the reason is returned", func() {
+				So(err, ShouldNotBeNil)
+				jobErr, ok := err.(*JobError)
+				So(ok, ShouldBeTrue)
+				So(jobErr.Reason, ShouldEqual, "invalidQuery")
+				So(jobErr.Location, ShouldEqual, "query")
+				So(jobErr.Message, ShouldEqual, "Unrecognized name: nme")
+				So(jobErr.IsInvalidQuery(), ShouldBeTrue)
+				So(jobErr.IsRateLimited(), ShouldBeFalse)
+				So(jobErr.IsAccessDenied(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a client whose service fails a query job after insert", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case req.Method == http.MethodPost:
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"status": {"state": "RUNNING"}
+				}`), nil
+			case strings.Contains(req.URL.Path, "/queries/"):
+				return jsonResponse(http.StatusBadRequest, `{"error": {"code": 400, "message": "Job failed"}}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {
+					"state": "DONE",
+					"errorResult": {"reason": "accessDenied", "message": "Access Denied: Table test"}
+				}
+			}`), nil
+		}))
+
+		Convey("When execute a query as a job", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPriority(PriorityBatch).Execute(&res)
+
+			Convey("Then a job error with the reason is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Job failed: accessDenied: Access Denied: Table test")
+				So(err.(*JobError).IsAccessDenied(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestAPIError(t *testing.T) {
+	Convey("Given error responses from bigquery API", t, func() {
+		cases := []struct {
+			code             int
+			reason           string
+			notFound         bool
+			permissionDenied bool
+			rateLimited      bool
+			unauthenticated  bool
+		}{
+			{code: http.StatusUnauthorized, reason: "authError", unauthenticated: true},
+			{code: http.StatusNotFound, reason: "notFound", notFound: true},
+			{code: http.StatusForbidden, reason: "accessDenied", permissionDenied: true},
+			{code: http.StatusForbidden, reason: "rateLimitExceeded", rateLimited: true},
+			{code: http.StatusTooManyRequests, reason: "", rateLimited: true},
+			{code: http.StatusBadRequest, reason: "invalidQuery"},
+		}
+
+		for _, tc := range cases {
+			tc := tc
+			c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+				return jsonResponse(tc.code, `{"error": {"code": `+strconv.Itoa(tc.code)+`, "message": "failed", "errors": [{"reason": "`+tc.reason+`", "message": "failed"}]}}`), nil
+			}))
+
+			Convey("When execute a query failing with "+strconv.Itoa(tc.code)+" "+tc.reason, func() {
+				var res []nameRec
+				err := c.Query("SELECT name FROM test").Execute(&res)
+
+				Convey("Then an api error with the code is returned", func() {
+					So(err, ShouldNotBeNil)
+					apiErr, ok := err.(*APIError)
+					So(ok, ShouldBeTrue)
+					So(apiErr.Code, ShouldEqual, tc.code)
+					So(apiErr.Message, ShouldEqual, "failed")
+					So(apiErr.IsNotFound(), ShouldEqual, tc.notFound)
+					So(apiErr.IsPermissionDenied(), ShouldEqual, tc.permissionDenied)
+					So(apiErr.IsRateLimited(), ShouldEqual, tc.rateLimited)
+					So(apiErr.IsUnauthenticated(), ShouldEqual, tc.unauthenticated)
+				})
+			})
+		}
+	})
+
+	Convey("Given a client whose service reports a missing table", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusNotFound, `{"error": {"code": 404, "message": "Not found: Table winter_test00:bq_test.test_table"}}`), nil
+		}))
+
+		Convey("When delete the table", func() {
+			err := c.DeleteTable("test_table")
+
+			Convey("Then a not found api error is returned", func() {
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.IsNotFound(), ShouldBeTrue)
+				So(apiErr.Error(), ShouldContainSubstring, "Not found: Table winter_test00:bq_test.test_table")
+			})
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+const (
+	// CompressionNone is option not to compress exported files
+	CompressionNone Compression = "NONE"
+	// CompressionGzip is option to compress exported files by gzip
+	CompressionGzip Compression = "GZIP"
+)
+
+// Compression expresses compression of exported files
+type Compression string
+
+// ExportOptions for exporting a table
+type ExportOptions struct {
+	// DestinationFormat shares its values with loading
+	DestinationFormat SourceFormat
+	Compression       Compression
+	// FieldDelimiter is only for CSV
+	FieldDelimiter string
+}
+
+// ExportToGCS exports a table to given Google Cloud Storage URIs and waits for its completion
+// A JobError is returned if the extract job fails
+func (c *Client) ExportToGCS(tableID string, destinationURIs []string, opts ExportOptions) error {
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.runJob(context.Background(), &bigquery.Job{
+		Configuration: &bigquery.JobConfiguration{
+			Extract: &bigquery.JobConfigurationExtract{
+				DestinationUris:   destinationURIs,
+				DestinationFormat: string(opts.DestinationFormat),
+				Compression:       string(opts.Compression),
+				FieldDelimiter:    opts.FieldDelimiter,
+				SourceTable: &bigquery.TableReference{
+					ProjectId: ref.ProjectId,
+					DatasetId: ref.DatasetId,
+					TableId:   tableID,
+				},
+			},
+		},
+	})
+	return err
+}
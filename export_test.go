@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestExportToGCS(t *testing.T) {
+	Convey("Given a client whose service runs an extract job", t, func() {
+		var sent bigquery.Job
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "DONE"}
+			}`), nil
+		}))
+
+		Convey("When export a table to GCS", func() {
+			err := c.ExportToGCS("test_table", []string{"gs://bucket/test_table-*.csv.gz"}, ExportOptions{
+				DestinationFormat: SourceFormatCSV,
+				Compression:       CompressionGzip,
+				FieldDelimiter:    "\t",
+			})
+
+			Convey("Then the extract job is run with the options", func() {
+				So(err, ShouldBeNil)
+
+				extract := sent.Configuration.Extract
+				So(extract.SourceTable.ProjectId, ShouldEqual, "winter_test00")
+				So(extract.SourceTable.DatasetId, ShouldEqual, "bq_test")
+				So(extract.SourceTable.TableId, ShouldEqual, "test_table")
+				So(extract.DestinationUris, ShouldResemble, []string{"gs://bucket/test_table-*.csv.gz"})
+				So(extract.DestinationFormat, ShouldEqual, "CSV")
+				So(extract.Compression, ShouldEqual, "GZIP")
+				So(extract.FieldDelimiter, ShouldEqual, "\t")
+			})
+		})
+	})
+
+	Convey("Given a client whose service fails an extract job", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "DONE", "errorResult": {"reason": "accessDenied", "message": "Access Denied: bucket"}}
+			}`), nil
+		}))
+
+		Convey("When export a table to GCS", func() {
+			err := c.ExportToGCS("test_table", []string{"gs://bucket/test_table.json"}, ExportOptions{DestinationFormat: SourceFormatJSON})
+
+			Convey("Then a job error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Job failed: accessDenied: Access Denied: bucket")
+			})
+		})
+	})
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+const timestampFormat = "2006-01-02 15:04:05.999999 UTC"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// InsertOptions is options of a streaming insert
+type InsertOptions struct {
+	// SkipInvalidRows inserts valid rows even if other rows are invalid
+	// Invalid rows are still returned as an InsertError
+	SkipInvalidRows bool
+	// IgnoreUnknownValues ignores values not matching the table schema instead of failing the rows
+	IgnoreUnknownValues bool
+	// TemplateSuffix inserts rows into the table named with the suffix,
+	// which is created with the schema of the destination table if it doesn't exist
+	TemplateSuffix string
+	// InsertIDs is an insert ID of each row used by bigquery to deduplicate retried rows
+	// Rows are inserted without insert IDs and without retries if it is empty
+	InsertIDs []string
+}
+
+// InsertRowsByJSONWithOptions inserts new rows with options of a streaming insert
+func (c *Client) InsertRowsByJSONWithOptions(tableID string, rows []map[string]interface{}, opts InsertOptions) error {
+	if len(opts.InsertIDs) > 0 && len(rows) != len(opts.InsertIDs) {
+		return errors.New("Mismatched insert IDs")
+	}
+	return c.insertRows(tableID, rows, opts.InsertIDs, opts)
+}
+
+// InsertRowsByJSONWithIDs inserts new rows with insert IDs used by bigquery to deduplicate retried rows
+func (c *Client) InsertRowsByJSONWithIDs(tableID string, rows []map[string]interface{}, insertIDs []string) error {
+	if len(rows) != len(insertIDs) {
+		return errors.New("Mismatched insert IDs")
+	}
+	return c.insertRows(tableID, rows, insertIDs, InsertOptions{})
+}
+
+// InsertRowsByJSONWithKey inserts new rows using the value of a given key column as insert ID
+func (c *Client) InsertRowsByJSONWithKey(tableID string, rows []map[string]interface{}, keyColumn string) error {
+	insertIDs := make([]string, 0, len(rows))
+	for i := range rows {
+		key, ok := rows[i][keyColumn]
+		if !ok || key == nil {
+			return errors.New("Missing key column")
+		}
+		insertIDs = append(insertIDs, fmt.Sprint(key))
+	}
+	return c.insertRows(tableID, rows, insertIDs, InsertOptions{})
+}
+
+// InsertRowsByStruct inserts a given slice of structs as new rows into the desired table or returns an error
+// Column names are taken from bq or json tags, falling back to field names
+// time.Time fields are formatted as TIMESTAMP and unexported fields are skipped
+func (c *Client) InsertRowsByStruct(tableID string, rows interface{}) error {
+	rowsV := reflect.ValueOf(rows)
+	if rowsV.Kind() != reflect.Slice {
+		return errors.New("Not slice")
+	}
+
+	jsonRows := make([]map[string]interface{}, 0, rowsV.Len())
+	for i := 0; i < rowsV.Len(); i++ {
+		row, err := structToRow(rowsV.Index(i))
+		if err != nil {
+			return err
+		}
+		jsonRows = append(jsonRows, row)
+	}
+
+	return c.InsertRowsByJSON(tableID, jsonRows)
+}
+
+// structToRow converts a struct value into a row for streaming insert
+func structToRow(v reflect.Value) (map[string]interface{}, error) {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("Not struct")
+	}
+
+	t := v.Type()
+	row := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := columnName(t.Field(i))
+		if !ok {
+			continue
+		}
+
+		fieldV := v.Field(i)
+		if fieldV.Type() == timeType {
+			row[name] = fieldV.Interface().(time.Time).UTC().Format(timestampFormat)
+			continue
+		}
+		row[name] = fieldV.Interface()
+	}
+	return row, nil
+}
+
+// columnName gets a column name of a given struct field from bq or json tags
+// It returns false for an unexported or ignored field
+func columnName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+
+	for _, key := range []string{"bq", "json"} {
+		tag := f.Tag.Get(key)
+		if tag == "" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	return f.Name, true
+}
+
+// ValidateBeforeInsert validates rows inserted into a given table against its schema before sending them
+// A row missing a REQUIRED column or having an unknown column fails locally with a descriptive error,
+// and unknown columns are allowed with IgnoreUnknownValues of InsertOptions
+// The schema is fetched on the first insert and cached by the client
+func (c *Client) ValidateBeforeInsert(tableID string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.insertSchemas == nil {
+		c.insertSchemas = make(map[string][]*bigquery.TableFieldSchema)
+	}
+	if _, ok := c.insertSchemas[tableID]; !ok {
+		c.insertSchemas[tableID] = nil
+	}
+	return c
+}
+
+// insertSchema returns the cached schema of a table validated before inserts, fetching it if needed
+// It returns false if rows inserted into the table are not validated
+func (c *Client) insertSchema(tableID string) ([]*bigquery.TableFieldSchema, bool, error) {
+	c.mu.Lock()
+	fields, ok := c.insertSchemas[tableID]
+	c.mu.Unlock()
+	if !ok || fields != nil {
+		return fields, ok, nil
+	}
+
+	ref, err := c.dataset()
+	if err != nil {
+		return nil, true, err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return nil, true, err
+	}
+
+	var table *bigquery.Table
+	err = c.do(func() (err error) {
+		table, err = service.Tables.Get(ref.ProjectId, ref.DatasetId, tableID).Do()
+		return err
+	})
+	if err != nil {
+		return nil, true, err
+	}
+
+	fields = []*bigquery.TableFieldSchema{}
+	if table.Schema != nil {
+		fields = table.Schema.Fields
+	}
+	c.mu.Lock()
+	c.insertSchemas[tableID] = fields
+	c.mu.Unlock()
+	return fields, true, nil
+}
+
+// validateRows checks that given rows have all REQUIRED columns and no unknown columns of the table
+func (c *Client) validateRows(tableID string, rows []map[string]interface{}, opts InsertOptions) error {
+	fields, ok, err := c.insertSchema(tableID)
+	if err != nil || !ok {
+		return err
+	}
+
+	columns := make(map[string]*bigquery.TableFieldSchema, len(fields))
+	for _, field := range fields {
+		columns[strings.ToLower(field.Name)] = field
+	}
+
+	for i, row := range rows {
+		present := make(map[string]bool, len(row))
+		for key, value := range row {
+			if _, ok := columns[strings.ToLower(key)]; !ok {
+				if opts.IgnoreUnknownValues {
+					continue
+				}
+				return fmt.Errorf("Unknown column %s at row %d for table %s", key, i, tableID)
+			}
+			if value != nil {
+				present[strings.ToLower(key)] = true
+			}
+		}
+		for _, field := range fields {
+			if field.Mode == fieldModeRequired && !present[strings.ToLower(field.Name)] {
+				return fmt.Errorf("Missing REQUIRED column %s at row %d for table %s", field.Name, i, tableID)
+			}
+		}
+	}
+	return nil
+}
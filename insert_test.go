@@ -0,0 +1,322 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+type insertRec struct {
+	Name      string
+	Age       int       `json:"age"`
+	Score     float64   `bq:"total_score" json:"score"`
+	CreatedAt time.Time `bq:"created_at"`
+	Ignored   string    `bq:"-"`
+	secret    string
+}
+
+func TestInsertRowsByStruct(t *testing.T) {
+	Convey("Given a client whose service accepts streaming inserts", t, func() {
+		var sent bigquery.TableDataInsertAllRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{"kind": "bigquery#tableDataInsertAllResponse"}`), nil
+		}))
+
+		Convey("When insert a slice of structs", func() {
+			rows := []insertRec{
+				{
+					Name:      "test_name",
+					Age:       26,
+					Score:     12.5,
+					CreatedAt: time.Date(2015, 2, 3, 6, 2, 3, 461000000, time.UTC),
+					Ignored:   "ignored",
+					secret:    "secret",
+				},
+			}
+			err := c.InsertRowsByStruct("test_table", rows)
+
+			Convey("Then rows are sent with column names from tags", func() {
+				So(err, ShouldBeNil)
+				So(len(sent.Rows), ShouldEqual, 1)
+				So(sent.Rows[0].Json, ShouldResemble, map[string]bigquery.JsonValue{
+					"Name":        "test_name",
+					"age":         26.0,
+					"total_score": 12.5,
+					"created_at":  "2015-02-03 06:02:03.461 UTC",
+				})
+			})
+		})
+
+		Convey("When insert a non slice value", func() {
+			err := c.InsertRowsByStruct("test_table", insertRec{})
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Not slice")
+			})
+		})
+	})
+}
+
+func TestInsertRowsByJSONWithIDs(t *testing.T) {
+	Convey("Given a client whose service accepts streaming inserts", t, func() {
+		var sent bigquery.TableDataInsertAllRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{"kind": "bigquery#tableDataInsertAllResponse"}`), nil
+		}))
+		rows := []map[string]interface{}{
+			{"id": 1, "name": "test_name1"},
+			{"id": 2, "name": "test_name2"},
+		}
+
+		Convey("When insert rows with insert IDs", func() {
+			err := c.InsertRowsByJSONWithIDs("test_table", rows, []string{"a", "b"})
+
+			Convey("Then rows are sent with insert IDs", func() {
+				So(err, ShouldBeNil)
+				So(len(sent.Rows), ShouldEqual, 2)
+				So(sent.Rows[0].InsertId, ShouldEqual, "a")
+				So(sent.Rows[1].InsertId, ShouldEqual, "b")
+			})
+		})
+
+		Convey("When insert rows with mismatched insert IDs", func() {
+			err := c.InsertRowsByJSONWithIDs("test_table", rows, []string{"a"})
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Mismatched insert IDs")
+			})
+		})
+
+		Convey("When insert rows with a key column", func() {
+			err := c.InsertRowsByJSONWithKey("test_table", rows, "id")
+
+			Convey("Then rows are sent with insert IDs from the key column", func() {
+				So(err, ShouldBeNil)
+				So(sent.Rows[0].InsertId, ShouldEqual, "1")
+				So(sent.Rows[1].InsertId, ShouldEqual, "2")
+			})
+		})
+
+		Convey("When insert rows with a missing key column", func() {
+			err := c.InsertRowsByJSONWithKey("test_table", rows, "uuid")
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Missing key column")
+			})
+		})
+	})
+}
+
+func TestSetInsertBatchSize(t *testing.T) {
+	Convey("Given a client whose service rejects the first row of each request", t, func() {
+		var sent []bigquery.TableDataInsertAllRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			var request bigquery.TableDataInsertAllRequest
+			json.NewDecoder(req.Body).Decode(&request)
+			sent = append(sent, request)
+			return jsonResponse(http.StatusOK, `{
+				"kind": "bigquery#tableDataInsertAllResponse",
+				"insertErrors": [{"index": 0, "errors": [{"reason": "invalid", "message": "invalid row"}]}]
+			}`), nil
+		}))
+		rows := make([]map[string]interface{}, 5)
+		ids := make([]string, 5)
+		for i := range rows {
+			rows[i] = map[string]interface{}{"id": i}
+			ids[i] = strconv.Itoa(i)
+		}
+
+		Convey("When insert more rows than the batch size", func() {
+			err := c.SetInsertBatchSize(2).InsertRowsByJSONWithIDs("test_table", rows, ids)
+
+			Convey("Then rows are sent in multiple requests and errors are aggregated", func() {
+				So(sent, ShouldHaveLength, 3)
+				So(sent[0].Rows, ShouldHaveLength, 2)
+				So(sent[2].Rows, ShouldHaveLength, 1)
+				So(sent[1].Rows[0].InsertId, ShouldEqual, "2")
+				So(sent[2].Rows[0].InsertId, ShouldEqual, "4")
+
+				So(err, ShouldNotBeNil)
+				insertErr, ok := err.(*InsertError)
+				So(ok, ShouldBeTrue)
+				So(insertErr.RowErrors(), ShouldHaveLength, 3)
+				So(insertErr.RowErrors()[0].Index, ShouldEqual, 0)
+				So(insertErr.RowErrors()[1].Index, ShouldEqual, 2)
+				So(insertErr.RowErrors()[2].Index, ShouldEqual, 4)
+			})
+		})
+
+		Convey("When insert rows within the default batch size", func() {
+			c.InsertRowsByJSON("test_table", rows)
+
+			Convey("Then rows are sent in a request", func() {
+				So(sent, ShouldHaveLength, 1)
+				So(sent[0].Rows, ShouldHaveLength, 5)
+			})
+		})
+	})
+}
+
+func TestInsertRowsByJSONWithOptions(t *testing.T) {
+	Convey("Given a client whose service accepts streaming inserts", t, func() {
+		var sent bigquery.TableDataInsertAllRequest
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{"kind": "bigquery#tableDataInsertAllResponse"}`), nil
+		}))
+		rows := []map[string]interface{}{{"name": "test_name1"}}
+
+		Convey("When insert rows with options", func() {
+			err := c.InsertRowsByJSONWithOptions("test_table", rows, InsertOptions{
+				SkipInvalidRows:     true,
+				IgnoreUnknownValues: true,
+				TemplateSuffix:      "_20150203",
+			})
+
+			Convey("Then the request has fields from the options", func() {
+				So(err, ShouldBeNil)
+				So(sent.SkipInvalidRows, ShouldBeTrue)
+				So(sent.IgnoreUnknownValues, ShouldBeTrue)
+				So(sent.TemplateSuffix, ShouldEqual, "_20150203")
+				So(sent.Rows, ShouldHaveLength, 1)
+				So(sent.Rows[0].InsertId, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When insert rows with insert IDs and options", func() {
+			err := c.InsertRowsByJSONWithOptions("test_table", rows, InsertOptions{
+				SkipInvalidRows: true,
+				InsertIDs:       []string{"id_1"},
+			})
+
+			Convey("Then each row has its insert ID with the options", func() {
+				So(err, ShouldBeNil)
+				So(sent.SkipInvalidRows, ShouldBeTrue)
+				So(sent.Rows[0].InsertId, ShouldEqual, "id_1")
+			})
+		})
+
+		Convey("When insert rows with empty insert IDs", func() {
+			err := c.InsertRowsByJSONWithOptions("test_table", rows, InsertOptions{
+				InsertIDs: []string{},
+			})
+
+			Convey("Then rows are inserted without insert IDs", func() {
+				So(err, ShouldBeNil)
+				So(sent.Rows, ShouldHaveLength, 1)
+				So(sent.Rows[0].InsertId, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When insert rows with a mismatched number of insert IDs", func() {
+			err := c.InsertRowsByJSONWithOptions("test_table", rows, InsertOptions{
+				InsertIDs: []string{"id_1", "id_2"},
+			})
+
+			Convey("Then error occurs", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Mismatched insert IDs")
+			})
+		})
+
+		Convey("When insert rows without options", func() {
+			err := c.InsertRowsByJSON("test_table", rows)
+
+			Convey("Then the request has no options", func() {
+				So(err, ShouldBeNil)
+				So(sent.SkipInvalidRows, ShouldBeFalse)
+				So(sent.IgnoreUnknownValues, ShouldBeFalse)
+				So(sent.TemplateSuffix, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestValidateBeforeInsert(t *testing.T) {
+	Convey("Given a client whose table has a REQUIRED column", t, func() {
+		var gets, inserts int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				gets++
+				return jsonResponse(http.StatusOK, `{
+					"schema": {"fields": [
+						{"name": "id", "type": "INTEGER", "mode": "REQUIRED"},
+						{"name": "name", "type": "STRING", "mode": "NULLABLE"}
+					]}
+				}`), nil
+			}
+			inserts++
+			return jsonResponse(http.StatusOK, `{"kind": "bigquery#tableDataInsertAllResponse"}`), nil
+		}))
+		c.ValidateBeforeInsert("test_table")
+
+		Convey("When insert rows missing the REQUIRED column", func() {
+			err := c.InsertRowsByJSON("test_table", []map[string]interface{}{
+				{"id": 1, "name": "test_name1"},
+				{"name": "test_name2"},
+			})
+
+			Convey("Then err is returned without sending rows", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Missing REQUIRED column id at row 1 for table test_table")
+				So(inserts, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When insert rows with a null REQUIRED column", func() {
+			err := c.InsertRowsByJSON("test_table", []map[string]interface{}{{"id": nil, "name": "test_name1"}})
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Missing REQUIRED column id at row 0 for table test_table")
+			})
+		})
+
+		Convey("When insert rows with an unknown column", func() {
+			rows := []map[string]interface{}{{"id": 1, "age": 26}}
+			err := c.InsertRowsByJSON("test_table", rows)
+
+			Convey("Then err is returned unless unknown values are ignored", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Unknown column age at row 0 for table test_table")
+
+				err = c.InsertRowsByJSONWithOptions("test_table", rows, InsertOptions{IgnoreUnknownValues: true})
+				So(err, ShouldBeNil)
+				So(inserts, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When insert valid rows twice", func() {
+			rows := []map[string]interface{}{{"ID": 1}, {"id": 2, "name": "test_name2"}}
+			err1 := c.InsertRowsByJSON("test_table", rows)
+			err2 := c.InsertRowsByJSON("test_table", rows)
+
+			Convey("Then rows are sent and the schema is fetched once", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+				So(inserts, ShouldEqual, 2)
+				So(gets, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When insert rows into a table not validated", func() {
+			err := c.InsertRowsByJSON("other_table", []map[string]interface{}{{"age": 26}})
+
+			Convey("Then rows are sent without fetching the schema", func() {
+				So(err, ShouldBeNil)
+				So(gets, ShouldEqual, 0)
+				So(inserts, ShouldEqual, 1)
+			})
+		})
+	})
+}
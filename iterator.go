@@ -0,0 +1,176 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"reflect"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// RowIterator iterates rows of query results, fetching pages on demand
+type RowIterator struct {
+	p     *pager
+	rows  []*bigquery.TableRow
+	index int
+	// count is the number of rows returned so far
+	count int
+
+	opts    *convertOptions
+	elemT   reflect.Type
+	indexes []int
+}
+
+// Iterator executes a given query and returns an iterator over its rows
+// Only the first page is fetched until rows of the next page are required
+func (q *Query) Iterator() (*RowIterator, error) {
+	p, err := q.newPager()
+	if err != nil {
+		return nil, err
+	}
+
+	it := &RowIterator{p: p}
+	if err := it.fetch(); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// Read executes a given query and returns an iterator over its rows like Iterator
+// No page is fetched until the first row is required, so errors of the query are returned by Next or NextInto
+func (q *Query) Read() (*RowIterator, error) {
+	p, err := q.newPager()
+	if err != nil {
+		return nil, err
+	}
+	return &RowIterator{p: p}, nil
+}
+
+// Next returns the next row or io.EOF after the last row
+func (it *RowIterator) Next() (*bigquery.TableRow, error) {
+	for it.index >= len(it.rows) {
+		if err := it.fetch(); err != nil {
+			return nil, err
+		}
+	}
+
+	row := it.rows[it.index]
+	it.index++
+	it.count++
+	return row, nil
+}
+
+// NextInto decodes the next row into dst or returns io.EOF after the last row
+// dst must be a pointer to a struct, which is reset before decoding, and only the current page is kept in memory
+// Malformed rows are skipped if the query skips them
+func (it *RowIterator) NextInto(dst interface{}) error {
+	dstV := reflect.ValueOf(dst)
+	if dstV.Kind() != reflect.Ptr || dstV.Elem().Kind() != reflect.Struct {
+		return errors.New("Not pointer to struct")
+	}
+	dstV = dstV.Elem()
+	if it.opts == nil {
+		it.opts = it.p.q.convertOptions()
+	}
+
+	for {
+		row, err := it.Next()
+		if err != nil {
+			return err
+		}
+		index := it.count - 1
+		ok, err := it.opts.wellFormed(it.p.fields, row.F, index)
+		if !ok {
+			if err != nil {
+				return err
+			}
+			it.p.q.addSkipped(0, it.opts.skipped)
+			it.opts.skipped = nil
+			continue
+		}
+
+		if it.elemT != dstV.Type() {
+			it.indexes, err = it.opts.fieldIndexes(it.p.fields, dstV.Type())
+			if err != nil {
+				return err
+			}
+			it.elemT = dstV.Type()
+		}
+		dstV.Set(reflect.Zero(dstV.Type()))
+		return it.opts.convertRow(it.p.fields, row.F, dstV, it.indexes)
+	}
+}
+
+// Schema returns fields of the query results
+// It is nil for an iterator by Read until the first row is required
+func (it *RowIterator) Schema() []*bigquery.TableFieldSchema {
+	return it.p.fields
+}
+
+func (it *RowIterator) fetch() error {
+	rows, err := it.p.next()
+	if err != nil {
+		return err
+	}
+	it.rows = rows
+	it.index = 0
+	return nil
+}
+
+// ForEach executes a given query and decodes each row into elem before calling fn
+// elem must be a pointer to a struct, which is reset and reused for each row without accumulating rows
+// The iteration stops at the first error returned by fn and the error is returned
+func (q *Query) ForEach(elem interface{}, fn func() error) error {
+	elemV := reflect.ValueOf(elem)
+	if elemV.Kind() != reflect.Ptr || elemV.Elem().Kind() != reflect.Struct {
+		return errors.New("Not pointer to struct")
+	}
+	elemV = elemV.Elem()
+
+	p, err := q.newPager()
+	if err != nil {
+		return err
+	}
+
+	opts := q.convertOptions()
+	defer func() {
+		q.addSkipped(0, opts.skipped)
+	}()
+
+	var indexes []int
+	var index int
+	for {
+		rows, err := p.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			ok, err := opts.wellFormed(p.fields, row.F, index)
+			index++
+			if !ok {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if indexes == nil {
+				indexes, err = opts.fieldIndexes(p.fields, elemV.Type())
+				if err != nil {
+					return err
+				}
+			}
+
+			elemV.Set(reflect.Zero(elemV.Type()))
+			if err := opts.convertRow(p.fields, row.F, elemV, indexes); err != nil {
+				return err
+			}
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newFakePagedClient generates a client whose service returns rows of given names one per page
+func newFakePagedClient(names []string, requests *int) *Client {
+	return newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+		index := *requests
+		*requests++
+
+		body := `{
+			"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+			"jobComplete": true,
+			"totalRows": "` + strconv.Itoa(len(names)) + `",
+			"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+			"rows": [{"f": [{"v": "` + names[index] + `"}]}]`
+		if index < len(names)-1 {
+			body += `, "pageToken": "page_` + strconv.Itoa(index+2) + `"`
+		}
+		return jsonResponse(http.StatusOK, body+`}`), nil
+	}))
+}
+
+func TestIterator(t *testing.T) {
+	Convey("Given a client whose service returns results in three pages", t, func() {
+		var requests int
+		c := newFakePagedClient([]string{"test_name1", "test_name2", "test_name3"}, &requests)
+
+		Convey("When iterate rows of a query", func() {
+			it, err := c.Query("SELECT name FROM test").Iterator()
+			So(err, ShouldBeNil)
+
+			Convey("Then pages are fetched on demand", func() {
+				So(requests, ShouldEqual, 1)
+				So(it.Schema()[0].Name, ShouldEqual, "name")
+
+				var names []interface{}
+				for {
+					row, err := it.Next()
+					if err == io.EOF {
+						break
+					}
+					So(err, ShouldBeNil)
+					names = append(names, row.F[0].V)
+					So(requests, ShouldEqual, len(names))
+				}
+				So(names, ShouldResemble, []interface{}{"test_name1", "test_name2", "test_name3"})
+
+				_, err = it.Next()
+				So(err, ShouldEqual, io.EOF)
+				So(requests, ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func TestLimit(t *testing.T) {
+	Convey("Given a client whose service returns results in three pages", t, func() {
+		var requests int
+		c := newFakePagedClient([]string{"test_name1", "test_name2", "test_name3"}, &requests)
+
+		Convey("When execute a query with a limit", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Limit(2).Execute(&res)
+
+			Convey("Then pagination halts at the limit", func() {
+				So(err, ShouldBeNil)
+				So(requests, ShouldEqual, 2)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name1"}, {Name: "test_name2"}})
+			})
+		})
+
+		Convey("When iterate rows of a query with a limit", func() {
+			it, err := c.Query("SELECT name FROM test").Limit(1).Iterator()
+			So(err, ShouldBeNil)
+
+			Convey("Then the iterator ends at the limit", func() {
+				row, err := it.Next()
+				So(err, ShouldBeNil)
+				So(row.F[0].V, ShouldEqual, "test_name1")
+
+				_, err = it.Next()
+				So(err, ShouldEqual, io.EOF)
+				So(requests, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When set an invalid limit", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Limit(0).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid limit")
+			})
+		})
+	})
+
+	Convey("Given a client whose service returns three rows in a page", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "3",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name1"}]}, {"f": [{"v": "test_name2"}]}, {"f": [{"v": "test_name3"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute a query with a limit", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Limit(2).Execute(&res)
+
+			Convey("Then the page is trimmed at the limit", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name1"}, {Name: "test_name2"}})
+			})
+		})
+	})
+}
+
+func TestOnProgress(t *testing.T) {
+	Convey("Given a client whose service returns results in three pages", t, func() {
+		var requests int
+		c := newFakePagedClient([]string{"test_name1", "test_name2", "test_name3"}, &requests)
+
+		Convey("When execute a query with a progress function", func() {
+			var fetched, totals []uint64
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").OnProgress(func(n, total uint64) {
+				fetched = append(fetched, n)
+				totals = append(totals, total)
+			}).Execute(&res)
+
+			Convey("Then the function is called after each page", func() {
+				So(err, ShouldBeNil)
+				So(fetched, ShouldResemble, []uint64{1, 2, 3})
+				So(totals, ShouldResemble, []uint64{3, 3, 3})
+			})
+		})
+
+		Convey("When execute a query job with a progress function", func() {
+			var calls int
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPriority(PriorityBatch).OnProgress(func(n, total uint64) {
+				calls++
+			}).Execute(&res)
+
+			Convey("Then the function is called after each page", func() {
+				So(err, ShouldBeNil)
+				// the fake service replies the first page to the job insertion
+				So(len(res), ShouldEqual, 2)
+				So(calls, ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestRead(t *testing.T) {
+	Convey("Given a client whose service returns rows in three pages", t, func() {
+		var requests int
+		c := newFakePagedClient([]string{"test_name1", "test_name2", "test_name3"}, &requests)
+
+		Convey("When read rows into a struct one by one", func() {
+			it, err := c.Query("SELECT name FROM test").Read()
+			So(err, ShouldBeNil)
+			So(requests, ShouldEqual, 0)
+
+			var rec nameRec
+			var names []string
+			for {
+				err = it.NextInto(&rec)
+				if err != nil {
+					break
+				}
+				names = append(names, rec.Name)
+			}
+
+			Convey("Then pages are fetched lazily until io.EOF", func() {
+				So(err, ShouldEqual, io.EOF)
+				So(requests, ShouldEqual, 3)
+				So(names, ShouldResemble, []string{"test_name1", "test_name2", "test_name3"})
+				So(it.NextInto(&rec), ShouldEqual, io.EOF)
+			})
+		})
+
+		Convey("When read a row into a value other than a pointer to struct", func() {
+			it, err := c.Query("SELECT name FROM test").Read()
+			So(err, ShouldBeNil)
+			var rec nameRec
+			err = it.NextInto(rec)
+
+			Convey("Then error occurs without fetching rows", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Not pointer to struct")
+				So(requests, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestForEach(t *testing.T) {
+	type scoreRec struct {
+		Name  string
+		Score int64
+	}
+
+	Convey("Given a client whose service returns scores in three pages", t, func() {
+		var requests int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			requests++
+			body := `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "3",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}, {"name": "score", "type": "INTEGER"}]},
+				"rows": [{"f": [{"v": "test_name` + strconv.Itoa(requests) + `"}, {"v": "` + strconv.Itoa(requests*10) + `"}]}]`
+			if requests < 3 {
+				body += `, "pageToken": "page_` + strconv.Itoa(requests+1) + `"`
+			}
+			return jsonResponse(http.StatusOK, body+`}`), nil
+		}))
+
+		Convey("When sum scores with a callback", func() {
+			var rec scoreRec
+			var sum int64
+			var names []string
+			err := c.Query("SELECT name, score FROM test").ForEach(&rec, func() error {
+				sum += rec.Score
+				names = append(names, rec.Name)
+				return nil
+			})
+
+			Convey("Then each row is decoded into the struct across pages", func() {
+				So(err, ShouldBeNil)
+				So(requests, ShouldEqual, 3)
+				So(sum, ShouldEqual, 60)
+				So(names, ShouldResemble, []string{"test_name1", "test_name2", "test_name3"})
+			})
+		})
+
+		Convey("When the callback returns an error", func() {
+			var rec scoreRec
+			var calls int
+			err := c.Query("SELECT name, score FROM test").ForEach(&rec, func() error {
+				calls++
+				return errors.New("Stop")
+			})
+
+			Convey("Then the iteration stops with the error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Stop")
+				So(calls, ShouldEqual, 1)
+				So(requests, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When iterate with a non pointer", func() {
+			err := c.Query("SELECT name, score FROM test").ForEach(scoreRec{}, func() error { return nil })
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Not pointer to struct")
+			})
+		})
+	})
+}
@@ -0,0 +1,374 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+const (
+	defaultPollInterval = time.Second
+
+	jobStateDone = "DONE"
+)
+
+// DryRunResult is an estimation of a query by dry run
+// CacheHit reports whether the query would be answered from the cache, and Fields is the schema of its results
+type DryRunResult struct {
+	TotalBytesProcessed int64
+	TotalBytesBilled    int64
+	CacheHit            bool
+	Fields              []*bigquery.TableFieldSchema
+}
+
+// Job is a handle of a job inserted into bigquery
+// Results are fetched and converted with options of the query submitting the job like Limit and IgnoreUnknownColumns
+type Job struct {
+	client *Client
+	ref    *bigquery.JobReference
+	query  *Query
+}
+
+// SubmitJob inserts a given query as a job and returns without waiting for its completion
+func (q *Query) SubmitJob() (*Job, error) {
+	service, err := q.getQueryService()
+	if err != nil {
+		return nil, err
+	}
+
+	insertedJob, err := q.insertJob(context.Background(), service, q.jobConfiguration())
+	if err != nil {
+		return nil, err
+	}
+	return &Job{
+		client: q.Client,
+		ref:    insertedJob.JobReference,
+		query:  q,
+	}, nil
+}
+
+// Reference returns the reference of the job
+func (j *Job) Reference() *bigquery.JobReference {
+	return j.ref
+}
+
+// Cancel requests bigquery to cancel the job
+// Cancellation is best-effort and the job may still complete or be billed
+func (j *Job) Cancel() error {
+	service, err := j.client.getQueryService()
+	if err != nil {
+		return err
+	}
+
+	return j.client.do(func() error {
+		return service.CancelJob(j.client.locatedJob(j.ref))
+	})
+}
+
+// Status gets the state of the job, PENDING, RUNNING or DONE
+// A JobError is returned with DONE if the job failed
+func (j *Job) Status() (string, error) {
+	service, err := j.client.getQueryService()
+	if err != nil {
+		return "", err
+	}
+
+	var job *bigquery.Job
+	err = j.client.do(func() (err error) {
+		job, err = service.GetJob(j.client.locatedJob(j.ref))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if job.Status == nil {
+		return "", nil
+	}
+	return job.Status.State, newJobError(job.Status)
+}
+
+// Wait polls the job until it is done or a given context is done
+// A JobError is returned if the job failed
+func (j *Job) Wait(ctx context.Context) error {
+	service, err := j.client.getQueryServiceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var job *bigquery.Job
+		err := j.client.doContext(ctx, func() (err error) {
+			job, err = service.GetJob(j.client.locatedJob(j.ref))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if job.Status != nil && job.Status.State == jobStateDone {
+			return newJobError(job.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(j.client.pollDelay()):
+		}
+	}
+}
+
+// Results fetches all rows of the query job and converts them into a given result
+// It waits for the completion of the job if it is still running, and a JobError is returned if the job failed
+func (j *Job) Results(result interface{}) error {
+	fields, rows, err := j.query.retrieveJobRows(context.Background(), j.ref)
+	if err != nil {
+		return err
+	}
+	return j.query.convert(fields, rows, 0, result)
+}
+
+// ResultsForJob fetches all rows of an existing query job and converts them into a given result
+// This resumes a job submitted before, polling while it is still running
+func (c *Client) ResultsForJob(jobID string, result interface{}) error {
+	return c.Query("").ResultsForJob(jobID, result)
+}
+
+// ResultsForJob fetches all rows of an existing query job like Client.ResultsForJob
+// Rows are fetched by the page size of the query and converted with its options like IgnoreUnknownColumns
+func (q *Query) ResultsForJob(jobID string, result interface{}) error {
+	return q.jobForID(jobID).Results(result)
+}
+
+// jobForID returns a handle of an existing job of a given ID in the project to run jobs of the client
+func (q *Query) jobForID(jobID string) *Job {
+	return &Job{
+		client: q.Client,
+		ref: &bigquery.JobReference{
+			ProjectId: q.Client.jobProject(),
+			JobId:     jobID,
+			Location:  q.Client.location,
+		},
+		query: q,
+	}
+}
+
+// QueryStats is statistics of an executed query
+type QueryStats struct {
+	TotalBytesProcessed int64
+	TotalBytesBilled    int64
+	CacheHit            bool
+	TotalSlotMs         int64
+}
+
+// Stats gets statistics of the job run by Execute or ExecuteWithChannel
+func (q *Query) Stats() (*QueryStats, error) {
+	if q.jobRef == nil {
+		return nil, errors.New("Not executed")
+	}
+
+	service, err := q.Client.getQueryService()
+	if err != nil {
+		return nil, err
+	}
+
+	var job *bigquery.Job
+	err = q.Client.do(func() (err error) {
+		job, err = service.GetJob(q.Client.locatedJob(q.jobRef))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newQueryStats(job), nil
+}
+
+// newQueryStats gets statistics of a given query job
+func newQueryStats(job *bigquery.Job) *QueryStats {
+	stats := &QueryStats{}
+	if job.Statistics != nil && job.Statistics.Query != nil {
+		stats.TotalBytesProcessed = job.Statistics.Query.TotalBytesProcessed
+		stats.TotalBytesBilled = job.Statistics.Query.TotalBytesBilled
+		stats.CacheHit = job.Statistics.Query.CacheHit
+		stats.TotalSlotMs = job.Statistics.Query.TotalSlotMs
+	}
+	return stats
+}
+
+// DestinationTable gets the table holding results of the query run by Execute or ExecuteWithChannel
+// It is the temp table of job configuration, or an anonymous table created by bigquery without it
+func (q *Query) DestinationTable() (*bigquery.TableReference, error) {
+	if q.jobRef == nil {
+		return nil, errors.New("Not executed")
+	}
+
+	service, err := q.Client.getQueryService()
+	if err != nil {
+		return nil, err
+	}
+
+	var job *bigquery.Job
+	err = q.Client.do(func() (err error) {
+		job, err = service.GetJob(q.Client.locatedJob(q.jobRef))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Configuration == nil || job.Configuration.Query == nil || job.Configuration.Query.DestinationTable == nil {
+		return nil, errors.New("No destination table")
+	}
+	return job.Configuration.Query.DestinationTable, nil
+}
+
+// DryRun validates a given query and estimates bytes processed without executing it
+func (q *Query) DryRun() (*DryRunResult, error) {
+	service, err := q.getQueryService()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &bigquery.Job{
+		JobReference: q.Client.newJobReference(q.project()),
+		Configuration: &bigquery.JobConfiguration{
+			DryRun: true,
+			Query: &bigquery.JobConfigurationQuery{
+				DefaultDataset: q.defaultDataset(),
+				Query:          q.QueryString,
+				UseQueryCache:  q.useCache,
+				UseLegacySql:   q.useLegacySQL(),
+			},
+		},
+	}
+	q.setJobParameters(job.Configuration.Query)
+
+	insertedJob, err := q.Client.insertJobContext(context.Background(), service, q.project(), job)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DryRunResult{}
+	if insertedJob.Statistics != nil {
+		result.TotalBytesProcessed = insertedJob.Statistics.TotalBytesProcessed
+		if insertedJob.Statistics.Query != nil {
+			result.TotalBytesProcessed = insertedJob.Statistics.Query.TotalBytesProcessed
+			result.TotalBytesBilled = insertedJob.Statistics.Query.TotalBytesBilled
+			result.CacheHit = insertedJob.Statistics.Query.CacheHit
+			if insertedJob.Statistics.Query.Schema != nil {
+				result.Fields = insertedJob.Statistics.Query.Schema.Fields
+			}
+		}
+	}
+	return result, nil
+}
+
+// runJob inserts a given job and waits for its completion until a given context is done
+func (c *Client) runJob(ctx context.Context, job *bigquery.Job) (*bigquery.Job, error) {
+	service, err := c.getQueryServiceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	insertedJob, err := c.insertJobContext(ctx, service, c.jobProject(), job)
+	if err != nil {
+		return nil, err
+	}
+	if insertedJob.JobReference != nil {
+		c.debugf("Submitted job %s", insertedJob.JobReference.JobId)
+	}
+
+	return c.waitJob(ctx, service, insertedJob)
+}
+
+// insertJobContext inserts a given job with the retry policy of the client
+// The job gets an ID before the first attempt, so a retry conflicting with the job accepted by a failed attempt
+// gets the job instead of inserting and billing it twice
+func (c *Client) insertJobContext(ctx context.Context, service QueryService, projectID string, job *bigquery.Job) (*bigquery.Job, error) {
+	if job.JobReference == nil {
+		job.JobReference = c.newJobReference(projectID)
+	}
+	if job.JobReference.JobId == "" {
+		job.JobReference.JobId = newJobIDSuffix()
+	}
+
+	var insertedJob *bigquery.Job
+	var attempts int
+	err := c.doContext(ctx, func() (err error) {
+		attempts++
+		insertedJob, err = service.InsertJob(projectID, job)
+		if attempts > 1 && isConflict(err) {
+			insertedJob, err = service.GetJob(c.locatedJob(job.JobReference))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return insertedJob, nil
+}
+
+// waitJob polls a given job until it is done and returns an error if the job failed
+// The error of a given context is returned if it is done while polling
+func (c *Client) waitJob(ctx context.Context, service QueryService, job *bigquery.Job) (*bigquery.Job, error) {
+	for job.Status == nil || job.Status.State != jobStateDone {
+		select {
+		case <-time.After(c.pollDelay()):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		ref := job.JobReference
+		err := c.doContext(ctx, func() (err error) {
+			job, err = service.GetJob(c.locatedJob(ref))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := newJobError(job.Status); err != nil {
+		return job, err
+	}
+	return job, nil
+}
+
+// pollDelay returns an interval to poll a running job
+func (c *Client) pollDelay() time.Duration {
+	if c.pollInterval > 0 {
+		return c.pollInterval
+	}
+	return defaultPollInterval
+}
+
+// newJobReference generates a reference for a new job of a given project in the location of the client
+// The job ID is generated to insert the job again safely on retries, and bigquery decides the location if it is not set
+func (c *Client) newJobReference(projectID string) *bigquery.JobReference {
+	return &bigquery.JobReference{
+		ProjectId: projectID,
+		JobId:     newJobIDSuffix(),
+		Location:  c.location,
+	}
+}
+
+// newJobIDSuffix generates a random suffix to make a job ID unique
+func newJobIDSuffix() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// jobLocation returns a location of a given job, falling back to the location of the client
+func (c *Client) jobLocation(ref *bigquery.JobReference) string {
+	if ref.Location != "" {
+		return ref.Location
+	}
+	return c.location
+}
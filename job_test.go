@@ -0,0 +1,452 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestDryRun(t *testing.T) {
+	Convey("Given a client whose service estimates a query", t, func() {
+		var sent bigquery.Job
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{
+				"statistics": {
+					"totalBytesProcessed": "1024",
+					"query": {
+						"totalBytesProcessed": "1024",
+						"totalBytesBilled": "0",
+						"cacheHit": true,
+						"schema": {"fields": [{"name": "name", "type": "STRING", "mode": "NULLABLE"}]}
+					}
+				}
+			}`), nil
+		}))
+
+		Convey("When dry run a query", func() {
+			res, err := c.Query("SELECT name FROM test").DryRun()
+
+			Convey("Then an estimation is returned without executing", func() {
+				So(err, ShouldBeNil)
+				So(sent.Configuration.DryRun, ShouldBeTrue)
+				So(sent.Configuration.Query.Query, ShouldEqual, "SELECT name FROM test")
+				So(sent.Configuration.Query.DefaultDataset.DatasetId, ShouldEqual, "bq_test")
+				So(res.TotalBytesProcessed, ShouldEqual, 1024)
+				So(res.TotalBytesBilled, ShouldEqual, 0)
+				So(res.CacheHit, ShouldBeTrue)
+				So(res.Fields, ShouldHaveLength, 1)
+				So(res.Fields[0].Name, ShouldEqual, "name")
+			})
+		})
+
+		Convey("When dry run a query with parameters without cache", func() {
+			_, err := c.Query("SELECT name FROM test WHERE age > @age").Param("age", 20).UseQueryCache(false).DryRun()
+
+			Convey("Then the options are sent with the estimation", func() {
+				So(err, ShouldBeNil)
+				So(*sent.Configuration.Query.UseQueryCache, ShouldBeFalse)
+				So(*sent.Configuration.Query.UseLegacySql, ShouldBeFalse)
+				So(sent.Configuration.Query.QueryParameters, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestStats(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var paths []string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.Method+" "+req.URL.Path)
+			if req.Method == http.MethodGet {
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"statistics": {"query": {
+						"totalBytesProcessed": "2048",
+						"totalBytesBilled": "10485760",
+						"cacheHit": false,
+						"totalSlotMs": "35"
+					}}
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When get stats before execute", func() {
+			stats, err := c.Query("SELECT name FROM test").Stats()
+
+			Convey("Then err is returned", func() {
+				So(stats, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Not executed")
+			})
+		})
+
+		Convey("When get stats after execute", func() {
+			var res []nameRec
+			q := c.Query("SELECT name FROM test")
+			So(q.Execute(&res), ShouldBeNil)
+			stats, err := q.Stats()
+
+			Convey("Then stats of the job are returned", func() {
+				So(err, ShouldBeNil)
+				So(paths[len(paths)-1], ShouldEndWith, "/projects/winter_test00/jobs/job_1")
+				So(stats.TotalBytesProcessed, ShouldEqual, 2048)
+				So(stats.TotalBytesBilled, ShouldEqual, 10485760)
+				So(stats.CacheHit, ShouldBeFalse)
+				So(stats.TotalSlotMs, ShouldEqual, 35)
+			})
+		})
+	})
+}
+
+func TestDestinationTable(t *testing.T) {
+	Convey("Given a client whose service runs a query job into a temp table", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet && strings.Contains(req.URL.Path, "/jobs/") {
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"configuration": {"query": {
+						"query": "SELECT name FROM test",
+						"destinationTable": {"projectId": "winter_test00", "datasetId": "bq_test", "tableId": "temp_table"}
+					}},
+					"status": {"state": "DONE"}
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When get the destination table before execute", func() {
+			_, err := c.Query("SELECT name FROM test").DestinationTable()
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Not executed")
+			})
+		})
+
+		Convey("When get the destination table after execute", func() {
+			var res []nameRec
+			q := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{TempTableName: "temp_table"})
+			So(q.Execute(&res), ShouldBeNil)
+			ref, err := q.DestinationTable()
+
+			Convey("Then the table of the completed job is returned", func() {
+				So(err, ShouldBeNil)
+				So(ref, ShouldResemble, &bigquery.TableReference{ProjectId: "winter_test00", DatasetId: "bq_test", TableId: "temp_table"})
+			})
+		})
+	})
+}
+
+func TestCancel(t *testing.T) {
+	Convey("Given a client whose service runs a query job", t, func() {
+		var paths []string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.Method+" "+req.URL.Path)
+			if strings.HasSuffix(req.URL.Path, "/cancel") {
+				return jsonResponse(http.StatusOK, `{"job": {"jobReference": {"projectId": "winter_test00", "jobId": "job_1"}}}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "RUNNING"}
+			}`), nil
+		}))
+
+		Convey("When submit a query and cancel the job", func() {
+			job, err := c.Query("SELECT name FROM test").SubmitJob()
+			So(err, ShouldBeNil)
+			err = job.Cancel()
+
+			Convey("Then the job is cancelled", func() {
+				So(err, ShouldBeNil)
+				So(job.Reference().JobId, ShouldEqual, "job_1")
+				So(paths, ShouldResemble, []string{
+					"POST /bigquery/v2/projects/winter_test00/jobs",
+					"POST /bigquery/v2/projects/winter_test00/jobs/job_1/cancel",
+				})
+			})
+		})
+	})
+}
+
+func TestSubmitJob(t *testing.T) {
+	Convey("Given a client whose service runs a query job in stages", t, func() {
+		states := []string{"PENDING", "RUNNING", "DONE"}
+		var polls int
+		var pageTokens []string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case req.Method == http.MethodPost:
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"status": {"state": "PENDING"}
+				}`), nil
+			case strings.Contains(req.URL.Path, "/queries/"):
+				pageToken := req.URL.Query().Get("pageToken")
+				pageTokens = append(pageTokens, pageToken)
+				if pageToken == "" {
+					return jsonResponse(http.StatusOK, `{
+						"jobComplete": true,
+						"totalRows": "2",
+						"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+						"rows": [{"f": [{"v": "test_name1"}]}],
+						"pageToken": "page_2"
+					}`), nil
+				}
+				return jsonResponse(http.StatusOK, `{
+					"jobComplete": true,
+					"totalRows": "2",
+					"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+					"rows": [{"f": [{"v": "test_name2"}]}]
+				}`), nil
+			}
+			state := states[polls]
+			if polls < len(states)-1 {
+				polls++
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "`+state+`"}
+			}`), nil
+		}))
+		c.pollInterval = time.Millisecond
+
+		Convey("When submit a query and get its status", func() {
+			job, err := c.Query("SELECT name FROM test").SubmitJob()
+			So(err, ShouldBeNil)
+			state, err := job.Status()
+
+			Convey("Then the current state is returned", func() {
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, "PENDING")
+			})
+		})
+
+		Convey("When submit a query and wait for it", func() {
+			job, err := c.Query("SELECT name FROM test").SubmitJob()
+			So(err, ShouldBeNil)
+			err = job.Wait(context.Background())
+
+			Convey("Then it returns after the job is done", func() {
+				So(err, ShouldBeNil)
+				So(polls, ShouldEqual, 2)
+
+				state, err := job.Status()
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, "DONE")
+			})
+		})
+
+		Convey("When submit a query and get its results", func() {
+			job, err := c.Query("SELECT name FROM test").SubmitJob()
+			So(err, ShouldBeNil)
+			var res []nameRec
+			err = job.Results(&res)
+
+			Convey("Then all pages are converted", func() {
+				So(err, ShouldBeNil)
+				So(pageTokens, ShouldResemble, []string{"", "page_2"})
+				So(res, ShouldResemble, []nameRec{{Name: "test_name1"}, {Name: "test_name2"}})
+			})
+		})
+
+		Convey("When wait for a job with a cancelled context", func() {
+			job, err := c.Query("SELECT name FROM test").SubmitJob()
+			So(err, ShouldBeNil)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err = job.Wait(ctx)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestResultsForJob(t *testing.T) {
+	Convey("Given a client whose service has a running query job", t, func() {
+		var paths []string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path)
+			if len(paths) == 1 {
+				return jsonResponse(http.StatusOK, `{"jobComplete": false}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+		c.pollInterval = time.Millisecond
+
+		Convey("When get results for the job", func() {
+			var res []nameRec
+			err := c.ResultsForJob("job_1", &res)
+
+			Convey("Then results are returned after the job completes", func() {
+				So(err, ShouldBeNil)
+				So(paths, ShouldResemble, []string{
+					"/bigquery/v2/projects/winter_test00/queries/job_1",
+					"/bigquery/v2/projects/winter_test00/queries/job_1",
+				})
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+	})
+
+	Convey("Given a client whose service has a query job of two pages", t, func() {
+		var paths []string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path)
+			return jsonResponse(http.StatusOK, `{
+				"jobComplete": true,
+				"totalRows": "4",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name1"}]}, {"f": [{"v": "test_name2"}]}],
+				"pageToken": "page_2"
+			}`), nil
+		}))
+
+		Convey("When get results for the job with a limit and progress", func() {
+			var progress []uint64
+			var res []nameRec
+			err := c.Query("").Limit(2).OnProgress(func(fetched, total uint64) {
+				progress = append(progress, fetched)
+			}).ResultsForJob("job_1", &res)
+
+			Convey("Then rows are fetched like results of a query", func() {
+				So(err, ShouldBeNil)
+				So(paths, ShouldHaveLength, 1)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name1"}, {Name: "test_name2"}})
+				So(progress, ShouldResemble, []uint64{2})
+			})
+		})
+	})
+
+	Convey("Given a client whose service has a failed query job", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if strings.Contains(req.URL.Path, "/queries/") {
+				return jsonResponse(http.StatusBadRequest, `{"error": {"code": 400, "message": "job failed"}}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "DONE", "errorResult": {"reason": "invalidQuery", "message": "Syntax error"}}
+			}`), nil
+		}))
+
+		Convey("When get results for the job", func() {
+			var res []nameRec
+			err := c.ResultsForJob("job_1", &res)
+
+			Convey("Then a JobError is returned", func() {
+				So(err, ShouldNotBeNil)
+				jobErr, ok := err.(*JobError)
+				So(ok, ShouldBeTrue)
+				So(jobErr.Error(), ShouldContainSubstring, "Syntax error")
+			})
+		})
+	})
+}
+
+func TestJobConvertOptions(t *testing.T) {
+	Convey("Given a client whose service returns an extra column for a query job", t, func() {
+		svc := &fakeService{
+			resultsResponse: &bigquery.GetQueryResultsResponse{
+				JobReference: &bigquery.JobReference{ProjectId: "winter_test00", JobId: "job_1"},
+				JobComplete:  true,
+				TotalRows:    1,
+				Schema: &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+					{Name: "name", Type: "STRING"},
+					{Name: "extra", Type: "STRING"},
+				}},
+				Rows: []*bigquery.TableRow{{F: []*bigquery.TableCell{{V: "test_name"}, {V: "extra"}}}},
+			},
+		}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+		c.Dataset("winter_test00", "bq_test")
+		c.WithService(svc)
+
+		Convey("When get results of a submitted query ignoring unknown columns", func() {
+			job, err := c.Query("SELECT name, extra FROM test").IgnoreUnknownColumns().SubmitJob()
+			So(err, ShouldBeNil)
+			var res []nameRec
+			err = job.Results(&res)
+
+			Convey("Then the extra column is ignored", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+
+		Convey("When get results for an existing job with options of a query", func() {
+			var res []nameRec
+			err := c.Query("").IgnoreUnknownColumns().ResultsForJob("job_1", &res)
+
+			Convey("Then the extra column is ignored", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+
+		Convey("When get results for an existing job without options", func() {
+			var res []nameRec
+			err := c.ResultsForJob("job_1", &res)
+
+			Convey("Then the extra column fails the conversion", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestWaitJob(t *testing.T) {
+	Convey("Given a client whose service has a running job", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "RUNNING"}
+			}`), nil
+		}))
+		c.pollInterval = time.Hour
+		service, err := c.getQueryService()
+		So(err, ShouldBeNil)
+
+		Convey("When wait for the job with a canceled context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			job := &bigquery.Job{
+				JobReference: &bigquery.JobReference{ProjectId: "winter_test00", JobId: "job_1"},
+				Status:       &bigquery.JobStatus{State: "RUNNING"},
+			}
+			_, err := c.waitJob(ctx, service, job)
+
+			Convey("Then the error of the context is returned without polling", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(count, ShouldEqual, 0)
+			})
+		})
+	})
+}
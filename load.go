@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+const (
+	// SourceFormatCSV is CSV format
+	SourceFormatCSV SourceFormat = "CSV"
+	// SourceFormatJSON is newline delimited JSON format
+	SourceFormatJSON SourceFormat = "NEWLINE_DELIMITED_JSON"
+	// SourceFormatAvro is Avro format
+	SourceFormatAvro SourceFormat = "AVRO"
+	// SourceFormatParquet is Parquet format
+	SourceFormatParquet SourceFormat = "PARQUET"
+)
+
+// SourceFormat expresses a format of source data to load
+type SourceFormat string
+
+// LoadOptions for loading data into a table
+type LoadOptions struct {
+	SourceFormat      SourceFormat
+	WriteDisposition  WriteDisp
+	CreateDisposition CreateDisp
+	// Schema is optional if the table exists or the format is self-describing
+	Schema []*bigquery.TableFieldSchema
+}
+
+// LoadFromGCS loads data from given Google Cloud Storage URIs into a table and waits for its completion
+// A JobError is returned if the load job fails
+func (c *Client) LoadFromGCS(tableID string, sourceURIs []string, opts LoadOptions) error {
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	load := &bigquery.JobConfigurationLoad{
+		SourceUris:        sourceURIs,
+		SourceFormat:      string(opts.SourceFormat),
+		WriteDisposition:  string(opts.WriteDisposition),
+		CreateDisposition: string(opts.CreateDisposition),
+		DestinationTable: &bigquery.TableReference{
+			ProjectId: ref.ProjectId,
+			DatasetId: ref.DatasetId,
+			TableId:   tableID,
+		},
+	}
+	if opts.Schema != nil {
+		load.Schema = &bigquery.TableSchema{Fields: opts.Schema}
+	}
+
+	_, err = c.runJob(context.Background(), &bigquery.Job{
+		Configuration: &bigquery.JobConfiguration{
+			Load: load,
+		},
+	})
+	return err
+}
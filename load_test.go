@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestLoadFromGCS(t *testing.T) {
+	Convey("Given a client whose service runs a load job", t, func() {
+		var sent bigquery.Job
+		var polls int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				json.NewDecoder(req.Body).Decode(&sent)
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+					"status": {"state": "RUNNING"}
+				}`), nil
+			}
+			polls++
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {"state": "DONE"}
+			}`), nil
+		}))
+		c.pollInterval = time.Millisecond
+
+		Convey("When load data from GCS", func() {
+			err := c.LoadFromGCS("test_table", []string{"gs://bucket/data.json"}, LoadOptions{
+				SourceFormat:      SourceFormatJSON,
+				WriteDisposition:  WriteAppend,
+				CreateDisposition: CreateIfNeeded,
+				Schema:            []*bigquery.TableFieldSchema{{Name: "name", Type: "STRING"}},
+			})
+
+			Convey("Then the load job is run until done", func() {
+				So(err, ShouldBeNil)
+				So(polls, ShouldEqual, 1)
+
+				load := sent.Configuration.Load
+				So(load.SourceUris, ShouldResemble, []string{"gs://bucket/data.json"})
+				So(load.SourceFormat, ShouldEqual, "NEWLINE_DELIMITED_JSON")
+				So(load.WriteDisposition, ShouldEqual, "WRITE_APPEND")
+				So(load.CreateDisposition, ShouldEqual, "CREATE_IF_NEEDED")
+				So(load.DestinationTable.TableId, ShouldEqual, "test_table")
+				So(load.Schema.Fields[0].Name, ShouldEqual, "name")
+			})
+		})
+	})
+
+	Convey("Given a client whose service fails a load job", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"status": {
+					"state": "DONE",
+					"errorResult": {"reason": "invalid", "location": "gs://bucket/data.csv", "message": "Error while reading data"},
+					"errors": [{"reason": "invalid", "message": "Error while reading data"}]
+				}
+			}`), nil
+		}))
+
+		Convey("When load data from GCS", func() {
+			err := c.LoadFromGCS("test_table", []string{"gs://bucket/data.csv"}, LoadOptions{SourceFormat: SourceFormatCSV})
+
+			Convey("Then a job error is returned", func() {
+				So(err, ShouldNotBeNil)
+				jobErr, ok := err.(*JobError)
+				So(ok, ShouldBeTrue)
+				So(jobErr.Reason, ShouldEqual, "invalid")
+				So(jobErr.Location, ShouldEqual, "gs://bucket/data.csv")
+				So(len(jobErr.Errors), ShouldEqual, 1)
+				So(err.Error(), ShouldEqual, "Job failed: invalid: Error while reading data")
+			})
+		})
+	})
+}
@@ -0,0 +1,21 @@
+package client
+
+// Logger is an interface to receive debug logs of the client like submitted jobs, fetched pages and retries
+// Loggers like zap, logrus or slog can be adapted with a small wrapper
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+// SetLogger sets a logger of the client
+// Nothing is logged by default or with nil
+func (c *Client) SetLogger(l Logger) *Client {
+	c.logger = l
+	return c
+}
+
+// debugf logs a message with the logger if set
+func (c *Client) debugf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Debugf(format, args...)
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// capturingLogger is a Logger recording formatted messages
+type capturingLogger struct {
+	messages []string
+}
+
+func (l *capturingLogger) Debugf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestSetLogger(t *testing.T) {
+	Convey("Given a client with a logger whose service fails once with 503", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			if count == 1 {
+				return jsonResponse(http.StatusServiceUnavailable, `{"error": {"code": 503, "message": "backend error"}}`), nil
+			}
+			if strings.HasSuffix(req.URL.Path, "/insertAll") {
+				return jsonResponse(http.StatusOK, `{"kind": "bigquery#tableDataInsertAllResponse"}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+		logger := &capturingLogger{}
+		c.SetLogger(logger).WithRetry(2, time.Millisecond)
+
+		Convey("When execute a query", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then the retry, the job and the page are logged", func() {
+				So(err, ShouldBeNil)
+				So(logger.messages, ShouldHaveLength, 3)
+				So(logger.messages[0], ShouldStartWith, "Retrying after ")
+				So(logger.messages[0], ShouldContainSubstring, "(attempt 1/2)")
+				So(logger.messages[1], ShouldEqual, "Submitted query job job_1")
+				So(logger.messages[2], ShouldEqual, "Fetched a page of 1 rows (1/1)")
+			})
+		})
+
+		Convey("When insert rows with insert IDs", func() {
+			err := c.SetInsertBatchSize(1).InsertRowsByJSONWithIDs("test_table", []map[string]interface{}{{"name": "test_name1"}, {"name": "test_name2"}}, []string{"id1", "id2"})
+
+			Convey("Then each batch is logged", func() {
+				So(err, ShouldBeNil)
+				So(logger.messages, ShouldHaveLength, 3)
+				So(logger.messages[0], ShouldEqual, "Inserting 1 rows into test_table")
+				So(logger.messages[1], ShouldStartWith, "Retrying after ")
+				So(logger.messages[2], ShouldEqual, "Inserting 1 rows into test_table")
+			})
+		})
+
+		Convey("When execute a query without a logger", func() {
+			var res []nameRec
+			err := c.SetLogger(nil).Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then nothing is logged", func() {
+				So(err, ShouldBeNil)
+				So(logger.messages, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"errors"
+	"sync"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// errNoStartIndex is returned when rows of a query are not available by StartIndex
+var errNoStartIndex = errors.New("Rows are not available by start index")
+
+// SetConcurrency sets the max number of pages fetched concurrently by Execute
+// Pages after the first one are fetched at offsets once the number of result rows is known,
+// and they are fetched sequentially if the job doesn't return rows by offsets
+// Rows are only read through the REST API since the Storage Read API is not supported
+func (q *Query) SetConcurrency(n int) *Query {
+	if n <= 0 {
+		q.err = errors.New("Invalid concurrency")
+		return q
+	}
+	q.concurrency = n
+	return q
+}
+
+// parallelizable reports whether the rest of rows can be fetched at offsets
+func (p *pager) parallelizable() bool {
+	return !p.done && p.jobRef != nil && len(p.pageToken) != 0 && p.size > 0 && p.totalRows > p.rowCount
+}
+
+// fetchParallel fetches the rest of rows by pages at offsets with a given number of workers
+// It returns nil without error to fall back to fetching sequentially if rows are not available by offsets
+func (p *pager) fetchParallel(workers int) ([]*bigquery.TableRow, error) {
+	end := p.totalRows
+	if p.limit > 0 && p.limit < end {
+		end = p.limit
+	}
+
+	var offsets []uint64
+	for offset := p.rowCount; offset < end; offset += uint64(p.size) {
+		offsets = append(offsets, offset)
+	}
+	pages := make([][]*bigquery.TableRow, len(offsets))
+	errs := make([]error, len(offsets))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				count := uint64(p.size)
+				if end-offsets[i] < count {
+					count = end - offsets[i]
+				}
+				pages[i], errs[i] = p.fetchRange(offsets[i], count)
+			}
+		}()
+	}
+	for i := range offsets {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var fallback bool
+	for _, err := range errs {
+		if err == errNoStartIndex {
+			fallback = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fallback {
+		return nil, nil
+	}
+
+	rows := make([]*bigquery.TableRow, 0, end-p.rowCount)
+	for _, page := range pages {
+		rows = append(rows, page...)
+	}
+	p.rowCount = end
+	p.done = true
+	p.progress()
+	return rows, nil
+}
+
+// fetchRange fetches a given number of rows from an offset
+// bigquery may return fewer rows than requested, so the rest is requested until all rows are fetched
+func (p *pager) fetchRange(offset uint64, count uint64) ([]*bigquery.TableRow, error) {
+	rows := make([]*bigquery.TableRow, 0, count)
+	for uint64(len(rows)) < count {
+		got := uint64(len(rows))
+		opts := QueryResultsOptions{
+			StartIndex: offset + got,
+			MaxResults: int64(count - got),
+		}
+
+		var qrr *bigquery.GetQueryResultsResponse
+		err := p.q.Client.doContext(p.ctx, func() (err error) {
+			qrr, err = p.service.GetQueryResults(p.q.Client.locatedJob(p.jobRef), opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !qrr.JobComplete || len(qrr.Rows) == 0 {
+			return nil, errNoStartIndex
+		}
+		rows = append(rows, qrr.Rows...)
+		p.q.Client.debugf("Fetched a page of %d rows at %d", len(qrr.Rows), offset+got)
+	}
+	return rows[:count], nil
+}
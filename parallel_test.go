@@ -0,0 +1,136 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newFakeOffsetClient generates a client whose service returns rows of given names from StartIndex
+// Rows are only returned by StartIndex if byOffset is true
+func newFakeOffsetClient(names []string, byOffset bool, startIndexes *[]string) *Client {
+	var mu sync.Mutex
+	return newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		start := 0
+		if token := query.Get("pageToken"); token != "" {
+			start, _ = strconv.Atoi(strings.TrimPrefix(token, "page_"))
+		}
+		if index := query.Get("startIndex"); index != "" {
+			mu.Lock()
+			*startIndexes = append(*startIndexes, index)
+			mu.Unlock()
+			if byOffset {
+				start, _ = strconv.Atoi(index)
+			} else {
+				start = len(names)
+			}
+		}
+		size := 2
+		if maxResults := query.Get("maxResults"); maxResults != "" {
+			size, _ = strconv.Atoi(maxResults)
+		}
+
+		var cells []string
+		for i := start; i < start+size && i < len(names); i++ {
+			cells = append(cells, `{"f": [{"v": "`+names[i]+`"}]}`)
+		}
+		body := `{
+			"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+			"jobComplete": true,
+			"totalRows": "` + strconv.Itoa(len(names)) + `",
+			"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+			"rows": [` + strings.Join(cells, ",") + `]`
+		if start+size < len(names) {
+			body += `, "pageToken": "page_` + strconv.Itoa(start+size) + `"`
+		}
+		return jsonResponse(http.StatusOK, body+`}`), nil
+	}))
+}
+
+func TestSetConcurrency(t *testing.T) {
+	names := []string{"test_name1", "test_name2", "test_name3", "test_name4", "test_name5", "test_name6", "test_name7"}
+	expected := make([]nameRec, 0, len(names))
+	for _, name := range names {
+		expected = append(expected, nameRec{Name: name})
+	}
+
+	Convey("Given a client whose service returns rows by offsets", t, func() {
+		var startIndexes []string
+		c := newFakeOffsetClient(names, true, &startIndexes)
+
+		Convey("When execute a query concurrently", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPageSize(2).SetConcurrency(3).Execute(&res)
+
+			Convey("Then pages are fetched at offsets and rows keep their order", func() {
+				So(err, ShouldBeNil)
+				So(startIndexes, ShouldHaveLength, 3)
+				So(startIndexes, ShouldContain, "2")
+				So(startIndexes, ShouldContain, "4")
+				So(startIndexes, ShouldContain, "6")
+				So(res, ShouldResemble, expected)
+			})
+		})
+
+		Convey("When execute a query concurrently with a limit", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPageSize(2).SetConcurrency(3).Limit(5).Execute(&res)
+
+			Convey("Then rows up to the limit are fetched", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, expected[:5])
+			})
+		})
+
+		Convey("When set an invalid concurrency", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetConcurrency(0).Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Invalid concurrency")
+			})
+		})
+	})
+
+	Convey("Given a client whose service doesn't return rows by offsets", t, func() {
+		var startIndexes []string
+		c := newFakeOffsetClient(names, false, &startIndexes)
+
+		Convey("When execute a query concurrently", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPageSize(2).SetConcurrency(3).Execute(&res)
+
+			Convey("Then pages are fetched sequentially", func() {
+				So(err, ShouldBeNil)
+				So(startIndexes, ShouldNotBeEmpty)
+				So(res, ShouldResemble, expected)
+			})
+		})
+	})
+}
+
+func BenchmarkExecuteConcurrently(b *testing.B) {
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = "test_name" + strconv.Itoa(i)
+	}
+	var startIndexes []string
+	c := newFakeOffsetClient(names, true, &startIndexes)
+
+	for _, concurrency := range []int{1, 4} {
+		b.Run("concurrency="+strconv.Itoa(concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var res []nameRec
+				if err := c.Query("SELECT name FROM test").SetPageSize(50).SetConcurrency(concurrency).Execute(&res); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+const (
+	parameterModeNamed      = "NAMED"
+	parameterModePositional = "POSITIONAL"
+)
+
+var errMixedParameters = errors.New("Named and positional parameters cannot be mixed")
+
+// Param adds a named parameter referred as @name in the query
+// Queries with parameters run in standard SQL unless legacy SQL is set explicitly, which fails the query
+func (q *Query) Param(name string, value interface{}) *Query {
+	if name == "" {
+		q.err = errors.New("Invalid parameter name")
+		return q
+	}
+	if q.paramMode == parameterModePositional {
+		q.err = errMixedParameters
+		return q
+	}
+	q.paramMode = parameterModeNamed
+	q.params = append(q.params, newQueryParameter(name, value))
+	return q
+}
+
+// Params adds positional parameters referred as ? in the query
+// Queries with parameters run in standard SQL unless legacy SQL is set explicitly, which fails the query
+func (q *Query) Params(values ...interface{}) *Query {
+	if q.paramMode == parameterModeNamed {
+		q.err = errMixedParameters
+		return q
+	}
+	q.paramMode = parameterModePositional
+	for _, value := range values {
+		q.params = append(q.params, newQueryParameter("", value))
+	}
+	return q
+}
+
+// QueryFromBuilder issues a new query built by a given query builder with its positional parameters
+func (c *Client) QueryFromBuilder(b *QueryBuilder) *Query {
+	query, params := b.Build()
+	q := c.Query(query)
+	if len(params) > 0 {
+		q.paramMode = parameterModePositional
+		q.params = params
+	}
+	return q
+}
+
+// setQueryParameters sets parameters of the query to a given query request
+func (q *Query) setQueryParameters(req *bigquery.QueryRequest) {
+	if len(q.params) == 0 {
+		return
+	}
+	req.QueryParameters = q.params
+	req.ParameterMode = q.paramMode
+}
+
+// setJobParameters sets parameters of the query to a given query job configuration
+func (q *Query) setJobParameters(config *bigquery.JobConfigurationQuery) {
+	if len(q.params) == 0 {
+		return
+	}
+	config.QueryParameters = q.params
+	config.ParameterMode = q.paramMode
+}
+
+// newQueryParameter generates a parameter of a given value, which is positional if the name is empty
+// string, integers, floats, bool, time.Time and []byte are sent as scalars, slices and arrays as ARRAY and structs as STRUCT,
+// and values of other types are sent as STRING formatted by fmt
+func newQueryParameter(name string, value interface{}) *bigquery.QueryParameter {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		// an untyped nil is sent as a NULL of STRING
+		return &bigquery.QueryParameter{
+			Name:           name,
+			ParameterType:  &bigquery.QueryParameterType{Type: "STRING"},
+			ParameterValue: &bigquery.QueryParameterValue{},
+		}
+	}
+	return &bigquery.QueryParameter{
+		Name:           name,
+		ParameterType:  parameterType(v.Type()),
+		ParameterValue: parameterValue(v),
+	}
+}
+
+// parameterType returns a type of a parameter for a given go type
+func parameterType(t reflect.Type) *bigquery.QueryParameterType {
+	switch {
+	case t == timeType:
+		return &bigquery.QueryParameterType{Type: "TIMESTAMP"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &bigquery.QueryParameterType{Type: "BYTES"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return parameterType(t.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &bigquery.QueryParameterType{Type: "INT64"}
+	case reflect.Float32, reflect.Float64:
+		return &bigquery.QueryParameterType{Type: "FLOAT64"}
+	case reflect.Bool:
+		return &bigquery.QueryParameterType{Type: "BOOL"}
+	case reflect.Slice, reflect.Array:
+		return &bigquery.QueryParameterType{Type: "ARRAY", ArrayType: parameterType(t.Elem())}
+	case reflect.Struct:
+		paramType := &bigquery.QueryParameterType{Type: "STRUCT"}
+		for _, f := range parameterFields(t) {
+			paramType.StructTypes = append(paramType.StructTypes, &bigquery.QueryParameterTypeStructTypes{
+				Name: parameterFieldName(f),
+				Type: parameterType(f.Type),
+			})
+		}
+		return paramType
+	}
+	return &bigquery.QueryParameterType{Type: "STRING"}
+}
+
+// parameterValue returns a value of a parameter for a given go value
+// A nil pointer is sent as NULL
+func parameterValue(v reflect.Value) *bigquery.QueryParameterValue {
+	switch {
+	case v.Type() == timeType:
+		return &bigquery.QueryParameterValue{Value: v.Interface().(time.Time).UTC().Format(timestampFormat)}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return &bigquery.QueryParameterValue{Value: base64.StdEncoding.EncodeToString(v.Bytes())}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return &bigquery.QueryParameterValue{}
+		}
+		return parameterValue(v.Elem())
+	case reflect.String:
+		return &bigquery.QueryParameterValue{Value: v.String()}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &bigquery.QueryParameterValue{Value: strconv.FormatInt(v.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &bigquery.QueryParameterValue{Value: strconv.FormatUint(v.Uint(), 10)}
+	case reflect.Float32:
+		return &bigquery.QueryParameterValue{Value: strconv.FormatFloat(v.Float(), 'g', -1, 32)}
+	case reflect.Float64:
+		return &bigquery.QueryParameterValue{Value: strconv.FormatFloat(v.Float(), 'g', -1, 64)}
+	case reflect.Bool:
+		return &bigquery.QueryParameterValue{Value: strconv.FormatBool(v.Bool())}
+	case reflect.Slice, reflect.Array:
+		// an empty array is sent as [] instead of NULL
+		paramValue := &bigquery.QueryParameterValue{
+			ArrayValues:     make([]*bigquery.QueryParameterValue, v.Len()),
+			ForceSendFields: []string{"ArrayValues"},
+		}
+		for i := 0; i < v.Len(); i++ {
+			paramValue.ArrayValues[i] = parameterValue(v.Index(i))
+		}
+		return paramValue
+	case reflect.Struct:
+		paramValue := &bigquery.QueryParameterValue{StructValues: map[string]bigquery.QueryParameterValue{}}
+		for _, f := range parameterFields(v.Type()) {
+			paramValue.StructValues[parameterFieldName(f)] = *parameterValue(v.FieldByIndex(f.Index))
+		}
+		return paramValue
+	}
+	return &bigquery.QueryParameterValue{Value: fmt.Sprint(v.Interface())}
+}
+
+// parameterFields returns exported fields of a struct sent as fields of a STRUCT parameter
+// A field tagged with `bq:"-"` is not sent
+func parameterFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || columnTag(f) == "-" {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// parameterFieldName returns a name of a field of a STRUCT parameter by the bq tag or the field name
+func parameterFieldName(f reflect.StructField) string {
+	if tag := columnTag(f); tag != "" {
+		return tag
+	}
+	return f.Name
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestParam(t *testing.T) {
+	Convey("Given a client with a fake service", t, func() {
+		svc := &fakeService{
+			queryResponse: &bigquery.QueryResponse{
+				JobReference: &bigquery.JobReference{ProjectId: "winter_test00", JobId: "job_1"},
+				JobComplete:  true,
+				TotalRows:    1,
+				Schema:       &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{{Name: "name", Type: "STRING"}}},
+				Rows:         []*bigquery.TableRow{{F: []*bigquery.TableCell{{V: "test_name1"}}}},
+			},
+		}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+		c.Dataset("winter_test00", "bq_test")
+		c.WithService(svc)
+
+		Convey("When execute a query with named parameters", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test WHERE age > @age AND name IN UNNEST(@names)").
+				Param("age", 20).
+				Param("names", []string{"test_name1", "test_name2"}).
+				Execute(&res)
+
+			Convey("Then parameters are sent in standard SQL", func() {
+				So(err, ShouldBeNil)
+				So(svc.queries, ShouldHaveLength, 1)
+				So(svc.queries[0].ParameterMode, ShouldEqual, "NAMED")
+				So(*svc.queries[0].UseLegacySql, ShouldBeFalse)
+				So(svc.queries[0].QueryParameters, ShouldHaveLength, 2)
+				So(svc.queries[0].QueryParameters[0].Name, ShouldEqual, "age")
+				So(svc.queries[0].QueryParameters[0].ParameterValue.Value, ShouldEqual, "20")
+				So(svc.queries[0].QueryParameters[1].ParameterType.ArrayType.Type, ShouldEqual, "STRING")
+			})
+		})
+
+		Convey("When execute a query built by a query builder", func() {
+			var res []nameRec
+			err := c.QueryFromBuilder(NewQueryBuilder().Select("name").From("test").Where("age > ?", 20)).Execute(&res)
+
+			Convey("Then positional parameters of the builder are sent", func() {
+				So(err, ShouldBeNil)
+				So(svc.queries[0].Query, ShouldEqual, "SELECT `name` FROM `test` WHERE (age > ?)")
+				So(svc.queries[0].ParameterMode, ShouldEqual, "POSITIONAL")
+				So(svc.queries[0].QueryParameters, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When execute a query without parameters", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then the dialect is left to the default", func() {
+				So(err, ShouldBeNil)
+				So(svc.queries[0].UseLegacySql, ShouldBeNil)
+				So(svc.queries[0].QueryParameters, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When mix named and positional parameters", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").Param("age", 20).Params("test_name1").Execute(&res)
+
+			Convey("Then error occurs without sending the query", func() {
+				So(err, ShouldEqual, errMixedParameters)
+				So(svc.queries, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestNewQueryParameter(t *testing.T) {
+	type address struct {
+		City    string `bq:"city"`
+		ZipCode int64
+		Note    string `bq:"-"`
+	}
+
+	Convey("Given values of go types", t, func() {
+		Convey("When generate parameters of them", func() {
+			var nilName *string
+			created := time.Date(2015, 2, 3, 4, 5, 6, 0, time.FixedZone("JST", 9*60*60))
+
+			Convey("Then they are converted into bigquery parameter types", func() {
+				So(newQueryParameter("", []byte("abc")), ShouldResemble, &bigquery.QueryParameter{
+					ParameterType:  &bigquery.QueryParameterType{Type: "BYTES"},
+					ParameterValue: &bigquery.QueryParameterValue{Value: "YWJj"},
+				})
+				So(newQueryParameter("created", created).ParameterValue.Value, ShouldEqual, "2015-02-02 19:05:06 UTC")
+				So(newQueryParameter("name", nilName), ShouldResemble, &bigquery.QueryParameter{
+					Name:           "name",
+					ParameterType:  &bigquery.QueryParameterType{Type: "STRING"},
+					ParameterValue: &bigquery.QueryParameterValue{},
+				})
+				So(newQueryParameter("scores", []float64{0.5, 1}), ShouldResemble, &bigquery.QueryParameter{
+					Name: "scores",
+					ParameterType: &bigquery.QueryParameterType{
+						Type:      "ARRAY",
+						ArrayType: &bigquery.QueryParameterType{Type: "FLOAT64"},
+					},
+					ParameterValue: &bigquery.QueryParameterValue{
+						ArrayValues:     []*bigquery.QueryParameterValue{{Value: "0.5"}, {Value: "1"}},
+						ForceSendFields: []string{"ArrayValues"},
+					},
+				})
+				So(newQueryParameter("address", &address{City: "tokyo", ZipCode: 1000001, Note: "ignored"}), ShouldResemble, &bigquery.QueryParameter{
+					Name: "address",
+					ParameterType: &bigquery.QueryParameterType{
+						Type: "STRUCT",
+						StructTypes: []*bigquery.QueryParameterTypeStructTypes{
+							{Name: "city", Type: &bigquery.QueryParameterType{Type: "STRING"}},
+							{Name: "ZipCode", Type: &bigquery.QueryParameterType{Type: "INT64"}},
+						},
+					},
+					ParameterValue: &bigquery.QueryParameterValue{
+						StructValues: map[string]bigquery.QueryParameterValue{
+							"city":    {Value: "tokyo"},
+							"ZipCode": {Value: "1000001"},
+						},
+					},
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// retryPolicy is a policy to retry transient errors from bigquery
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// WithRetry sets a retry policy for calls to bigquery
+// A call is attempted at most maxAttempts times while it fails with HTTP 429, 500 or 503,
+// waiting for a jittered exponential backoff starting from baseDelay between attempts
+// Rows inserted without insert IDs are not retried since bigquery can't deduplicate them
+func (c *Client) WithRetry(maxAttempts int, baseDelay time.Duration) *Client {
+	c.retry = &retryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+	}
+	return c
+}
+
+// do calls a given function with the retry policy of the client
+// An error response from bigquery API is returned as an APIError
+func (c *Client) do(call func() error) error {
+	return c.doContext(context.Background(), call)
+}
+
+// doContext calls a given function with the retry policy of the client until a given context is done
+// The error of the context is returned if it is done while waiting to retry
+func (c *Client) doContext(ctx context.Context, call func() error) error {
+	if c.retry == nil {
+		return newAPIError(call())
+	}
+
+	var err error
+	delay := c.retry.baseDelay
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil || attempt >= c.retry.maxAttempts || !isRetryable(err) {
+			return newAPIError(err)
+		}
+
+		wait := jitter(delay)
+		c.debugf("Retrying after %v (attempt %d/%d): %v", wait, attempt, c.retry.maxAttempts, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// isRetryable reports whether a given error is transient
+func isRetryable(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	if !ok {
+		return false
+	}
+
+	switch apiErr.Code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// isConflict reports whether a given error is a conflict with an existing resource like a job of the same ID
+func isConflict(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	return ok && apiErr.Code == http.StatusConflict
+}
+
+// jitter returns a random duration between d/2 and d
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
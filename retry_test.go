@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+const retryQueryResponse = `{
+	"jobComplete": true,
+	"totalRows": "1",
+	"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+	"rows": [{"f": [{"v": "test_name"}]}]
+}`
+
+func TestWithRetry(t *testing.T) {
+	Convey("Given a client whose service fails twice with 503", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			if count <= 2 {
+				return jsonResponse(http.StatusServiceUnavailable, `{"error": {"code": 503, "message": "backend error"}}`), nil
+			}
+			return jsonResponse(http.StatusOK, retryQueryResponse), nil
+		}))
+
+		Convey("When execute a query with retry", func() {
+			var res []nameRec
+			err := c.WithRetry(3, time.Millisecond).Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then results are returned after retries", func() {
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 3)
+				So(len(res), ShouldEqual, 1)
+				So(res[0].Name, ShouldEqual, "test_name")
+			})
+		})
+
+		Convey("When execute a query with too few attempts", func() {
+			var res []nameRec
+			err := c.WithRetry(2, time.Millisecond).Query("SELECT name FROM test").Execute(&res)
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(count, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given a client whose service fails with 400", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			return jsonResponse(http.StatusBadRequest, `{"error": {"code": 400, "message": "invalid query"}}`), nil
+		}))
+
+		Convey("When execute a query with retry", func() {
+			var res []nameRec
+			err := c.WithRetry(3, time.Millisecond).Query("SELECT").Execute(&res)
+
+			Convey("Then err is returned without retry", func() {
+				So(err, ShouldNotBeNil)
+				So(count, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestRetryWithContext(t *testing.T) {
+	Convey("Given a client whose service keeps failing with 503 and a long backoff", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			return jsonResponse(http.StatusServiceUnavailable, `{"error": {"code": 503, "message": "backend error"}}`), nil
+		}))
+		c.WithRetry(5, 30*time.Second)
+
+		Convey("When execute a query with a deadline expiring during the backoff", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").ExecuteContext(ctx, &res)
+
+			Convey("Then the retry stops with the error of the context", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+				So(count, ShouldEqual, 1)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+			})
+		})
+	})
+}
+
+func TestRetryInsertJob(t *testing.T) {
+	Convey("Given a client whose service fails to insert a job with 500 after accepting it", t, func() {
+		var jobIDs []string
+		var gets int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				gets++
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "accepted_job"},
+					"status": {"state": "RUNNING"}
+				}`), nil
+			}
+
+			var job bigquery.Job
+			json.NewDecoder(req.Body).Decode(&job)
+			jobIDs = append(jobIDs, job.JobReference.JobId)
+			if len(jobIDs) == 1 {
+				return jsonResponse(http.StatusInternalServerError, `{"error": {"code": 500, "message": "backend error"}}`), nil
+			}
+			return jsonResponse(http.StatusConflict, `{"error": {"code": 409, "message": "already exists"}}`), nil
+		}))
+		c.WithRetry(3, time.Millisecond)
+
+		Convey("When submit a query job", func() {
+			job, err := c.Query("SELECT name FROM test").SubmitJob()
+
+			Convey("Then the job is inserted with the same ID on both attempts and the accepted job is returned", func() {
+				So(err, ShouldBeNil)
+				So(jobIDs, ShouldHaveLength, 2)
+				So(jobIDs[0], ShouldNotBeEmpty)
+				So(jobIDs[1], ShouldEqual, jobIDs[0])
+				So(gets, ShouldEqual, 1)
+				So(job.Reference().JobId, ShouldEqual, "accepted_job")
+			})
+		})
+	})
+
+	Convey("Given a client whose service conflicts on the first attempt", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			return jsonResponse(http.StatusConflict, `{"error": {"code": 409, "message": "already exists"}}`), nil
+		}))
+		c.WithRetry(3, time.Millisecond)
+
+		Convey("When submit a query job", func() {
+			_, err := c.Query("SELECT name FROM test").SubmitJob()
+
+			Convey("Then the conflict is returned since the job was not inserted by the client", func() {
+				So(err, ShouldNotBeNil)
+				So(count, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestRetryInsertAll(t *testing.T) {
+	Convey("Given a client whose service fails to insert rows with 500 once", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			if count == 1 {
+				return jsonResponse(http.StatusInternalServerError, `{"error": {"code": 500, "message": "backend error"}}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{"kind": "bigquery#tableDataInsertAllResponse"}`), nil
+		}))
+		c.WithRetry(3, time.Millisecond)
+		rows := []map[string]interface{}{{"name": "test_name1"}, {"name": "test_name2"}}
+
+		Convey("When insert rows with insert IDs", func() {
+			err := c.InsertRowsByJSONWithIDs("test_table", rows, []string{"id1", "id2"})
+
+			Convey("Then the rows are inserted by a retry", func() {
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When insert rows without insert IDs", func() {
+			err := c.InsertRowsByJSON("test_table", rows)
+
+			Convey("Then err is returned without retry not to duplicate the rows", func() {
+				So(err, ShouldNotBeNil)
+				So(strings.Contains(err.Error(), "backend error"), ShouldBeTrue)
+				So(count, ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// Schema executes a given query without fetching rows and returns fields of its results
+func (q *Query) Schema() ([]*bigquery.TableFieldSchema, error) {
+	p, err := q.newPager()
+	if err != nil {
+		return nil, err
+	}
+
+	p.size = 0
+	if _, err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.fields, nil
+}
+
+// SchemaToGoStruct generates a Go struct type literal for given fields with bq tags
+// Field types are the ones Convert accepts, so TIMESTAMP columns are declared as int64 unix time in microseconds,
+// BYTES as []byte, and DATE, DATETIME, TIME, NUMERIC, BIGNUMERIC, GEOGRAPHY, INTERVAL and JSON as string
+// Columns of other types are declared as string to be filled by a Converter
+func SchemaToGoStruct(fields []*bigquery.TableFieldSchema) string {
+	var buf bytes.Buffer
+	writeStruct(&buf, fields)
+
+	// format aligns field types and tags as gofmt does
+	const prefix = "package p\n\ntype T "
+	src, err := format.Source([]byte(prefix + buf.String()))
+	if err != nil {
+		return buf.String()
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(src), prefix), "\n")
+}
+
+func writeStruct(buf *bytes.Buffer, fields []*bigquery.TableFieldSchema) {
+	buf.WriteString("struct {\n")
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%s ", goFieldName(field.Name))
+		if field.Mode == fieldModeRepeated {
+			buf.WriteString("[]")
+		}
+		switch field.Type {
+		case fieldTypeString:
+			buf.WriteString("string")
+		case fieldTypeInteger, fieldTypeTimestamp:
+			buf.WriteString("int64")
+		case fieldTypeFloat:
+			buf.WriteString("float64")
+		case fieldTypeBoolean:
+			buf.WriteString("bool")
+		case fieldTypeBytes:
+			buf.WriteString("[]byte")
+		case fieldTypeRecord:
+			writeStruct(buf, field.Fields)
+		default:
+			buf.WriteString("string")
+		}
+		fmt.Fprintf(buf, " `bq:\"%s\"`\n", field.Name)
+	}
+	buf.WriteString("}")
+}
+
+// goFieldName converts a column name like user_name into an exported field name like UserName
+func goFieldName(column string) string {
+	var name string
+	for _, part := range strings.FieldsFunc(column, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		first, size := utf8.DecodeRuneInString(part)
+		name += string(unicode.ToUpper(first)) + part[size:]
+	}
+	if first, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(first) {
+		name = "X" + name
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestSchema(t *testing.T) {
+	Convey("Given a client whose service completes a query", t, func() {
+		var queryRequest map[string]interface{}
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&queryRequest)
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "job_1"},
+				"jobComplete": true,
+				"totalRows": "10",
+				"schema": {"fields": [
+					{"name": "name", "type": "STRING"},
+					{"name": "age", "type": "INTEGER"}
+				]},
+				"pageToken": "page_2"
+			}`), nil
+		}))
+
+		Convey("When get the schema of a query", func() {
+			fields, err := c.Query("SELECT name, age FROM test").Schema()
+
+			Convey("Then fields are returned without fetching rows", func() {
+				So(err, ShouldBeNil)
+				So(queryRequest["maxResults"], ShouldEqual, 0)
+				So(len(fields), ShouldEqual, 2)
+				So(fields[0].Name, ShouldEqual, "name")
+				So(fields[1].Type, ShouldEqual, "INTEGER")
+			})
+		})
+	})
+}
+
+func TestSchemaToGoStruct(t *testing.T) {
+	Convey("Given fields of various types", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "user_name", Type: "STRING"},
+			{Mode: "REQUIRED", Name: "age", Type: "INTEGER"},
+			{Mode: "NULLABLE", Name: "score", Type: "FLOAT"},
+			{Mode: "NULLABLE", Name: "is_deleted", Type: "BOOLEAN"},
+			{Mode: "NULLABLE", Name: "created_at", Type: "TIMESTAMP"},
+			{Mode: "REPEATED", Name: "tags", Type: "STRING"},
+			{Mode: "NULLABLE", Name: "address", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+				{Mode: "NULLABLE", Name: "city", Type: "STRING"},
+			}},
+		}
+
+		Convey("When generate a struct", func() {
+			src := SchemaToGoStruct(fields)
+
+			Convey("Then a struct with types and bq tags is emitted", func() {
+				So(src, ShouldEqual, "struct {\n"+
+					"\tUserName  string   `bq:\"user_name\"`\n"+
+					"\tAge       int64    `bq:\"age\"`\n"+
+					"\tScore     float64  `bq:\"score\"`\n"+
+					"\tIsDeleted bool     `bq:\"is_deleted\"`\n"+
+					"\tCreatedAt int64    `bq:\"created_at\"`\n"+
+					"\tTags      []string `bq:\"tags\"`\n"+
+					"\tAddress   struct {\n"+
+					"\t\tCity string `bq:\"city\"`\n"+
+					"\t} `bq:\"address\"`\n"+
+					"}")
+			})
+		})
+	})
+
+	Convey("Given fields of types without a go type of their own", t, func() {
+		fields := []*bigquery.TableFieldSchema{
+			{Mode: "NULLABLE", Name: "avatar", Type: "BYTES"},
+			{Mode: "NULLABLE", Name: "birthday", Type: "DATE"},
+			{Mode: "NULLABLE", Name: "updated", Type: "DATETIME"},
+			{Mode: "NULLABLE", Name: "wakeup", Type: "TIME"},
+			{Mode: "NULLABLE", Name: "price", Type: "NUMERIC"},
+			{Mode: "NULLABLE", Name: "balance", Type: "BIGNUMERIC"},
+		}
+
+		Convey("When generate a struct and convert rows into it", func() {
+			src := SchemaToGoStruct(fields)
+
+			// the struct emitted above
+			var res []struct {
+				Avatar   []byte `bq:"avatar"`
+				Birthday string `bq:"birthday"`
+				Updated  string `bq:"updated"`
+				Wakeup   string `bq:"wakeup"`
+				Price    string `bq:"price"`
+				Balance  string `bq:"balance"`
+			}
+			rows := []*bigquery.TableRow{{F: []*bigquery.TableCell{
+				{V: "YWJj"}, {V: "2015-02-03"}, {V: "2015-02-03T04:05:06"}, {V: "04:05:06"}, {V: "123.456"}, {V: "1234567890.123456789012345678"},
+			}}}
+			err := Convert(fields, rows, &res)
+
+			Convey("Then the struct is filled by Convert", func() {
+				So(src, ShouldEqual, "struct {\n"+
+					"\tAvatar   []byte `bq:\"avatar\"`\n"+
+					"\tBirthday string `bq:\"birthday\"`\n"+
+					"\tUpdated  string `bq:\"updated\"`\n"+
+					"\tWakeup   string `bq:\"wakeup\"`\n"+
+					"\tPrice    string `bq:\"price\"`\n"+
+					"\tBalance  string `bq:\"balance\"`\n"+
+					"}")
+				So(err, ShouldBeNil)
+				So(res, ShouldHaveLength, 1)
+				So(res[0].Avatar, ShouldResemble, []byte("abc"))
+				So(res[0].Birthday, ShouldEqual, "2015-02-03")
+				So(res[0].Updated, ShouldEqual, "2015-02-03T04:05:06")
+				So(res[0].Wakeup, ShouldEqual, "04:05:06")
+				So(res[0].Price, ShouldEqual, "123.456")
+				So(res[0].Balance, ShouldEqual, "1234567890.123456789012345678")
+			})
+		})
+	})
+
+	Convey("Given a column starting with a multi-byte letter", t, func() {
+		Convey("When generate a field name", func() {
+			name := goFieldName("élan_vital")
+
+			Convey("Then the first rune is upper cased", func() {
+				So(name, ShouldEqual, "ÉlanVital")
+			})
+		})
+	})
+}
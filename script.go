@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"sort"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+// ScriptResult is a result of a statement in a multi-statement script
+// Rows are only fetched for the last statement, since results of a script are ones of its last statement
+type ScriptResult struct {
+	JobID               string
+	StatementType       string
+	Fields              []*bigquery.TableFieldSchema
+	Rows                []*bigquery.TableRow
+	NumDmlAffectedRows  int64
+	TotalBytesProcessed int64
+}
+
+// ExecuteScript executes a given multi-statement script in standard SQL and returns results of statements in order
+// Each statement runs as a child job of the script job, and a JobError is returned if the script fails
+func (q *Query) ExecuteScript() ([]ScriptResult, error) {
+	service, err := q.getQueryService()
+	if err != nil {
+		return nil, err
+	}
+
+	config := q.jobConfiguration()
+	config.Query.UseLegacySql = googleapi.Bool(false)
+	insertedJob, err := q.insertJob(context.Background(), service, config)
+	if err != nil {
+		return nil, err
+	}
+	q.jobRef = insertedJob.JobReference
+
+	doneJob, err := q.Client.waitJob(context.Background(), service, insertedJob)
+	if err != nil {
+		return nil, err
+	}
+
+	apiService, err := q.Client.getService()
+	if err != nil {
+		return nil, err
+	}
+	children, err := q.Client.listChildJobs(apiService, doneJob.JobReference)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ScriptResult, 0, len(children))
+	for _, child := range children {
+		results = append(results, newScriptResult(child.JobReference, child.Statistics))
+	}
+	if len(results) == 0 {
+		// a script of a single statement runs without child jobs
+		results = append(results, newScriptResult(doneJob.JobReference, doneJob.Statistics))
+	}
+
+	fields, rows, err := q.retrieveJobRows(context.Background(), doneJob.JobReference)
+	if err != nil {
+		return nil, err
+	}
+	last := &results[len(results)-1]
+	if len(last.Fields) == 0 {
+		last.Fields = fields
+	}
+	last.Rows = rows
+	return results, nil
+}
+
+// newScriptResult generates a result of a statement from statistics of its job
+func newScriptResult(ref *bigquery.JobReference, stats *bigquery.JobStatistics) ScriptResult {
+	var result ScriptResult
+	if ref != nil {
+		result.JobID = ref.JobId
+	}
+	if stats != nil && stats.Query != nil {
+		result.StatementType = stats.Query.StatementType
+		result.NumDmlAffectedRows = stats.Query.NumDmlAffectedRows
+		result.TotalBytesProcessed = stats.Query.TotalBytesProcessed
+		if stats.Query.Schema != nil {
+			result.Fields = stats.Query.Schema.Fields
+		}
+	}
+	return result
+}
+
+// listChildJobs lists all child jobs of a given script job in order of creation
+func (c *Client) listChildJobs(service *bigquery.Service, parent *bigquery.JobReference) ([]*bigquery.JobListJobs, error) {
+	var jobs []*bigquery.JobListJobs
+	var pageToken string
+	for {
+		call := service.Jobs.List(parent.ProjectId).ParentJobId(parent.JobId)
+		if len(pageToken) != 0 {
+			call.PageToken(pageToken)
+		}
+
+		var list *bigquery.JobList
+		err := c.do(func() (err error) {
+			list, err = call.Do()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, list.Jobs...)
+		if len(list.NextPageToken) == 0 {
+			break
+		}
+		pageToken = list.NextPageToken
+	}
+
+	// jobs are listed in reverse chronological order
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return creationTime(jobs[i]) < creationTime(jobs[j])
+	})
+	return jobs, nil
+}
+
+func creationTime(job *bigquery.JobListJobs) int64 {
+	if job.Statistics == nil {
+		return 0
+	}
+	return job.Statistics.CreationTime
+}
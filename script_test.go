@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+func TestExecuteScript(t *testing.T) {
+	Convey("Given a client whose service runs a script with two statements", t, func() {
+		var sent bigquery.Job
+		var parentJobID string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case req.Method == http.MethodPost:
+				json.NewDecoder(req.Body).Decode(&sent)
+				return jsonResponse(http.StatusOK, `{
+					"jobReference": {"projectId": "winter_test00", "jobId": "script_1"},
+					"status": {"state": "DONE"}
+				}`), nil
+			case strings.HasSuffix(req.URL.Path, "/jobs"):
+				parentJobID = req.URL.Query().Get("parentJobId")
+				return jsonResponse(http.StatusOK, `{
+					"jobs": [
+						{
+							"jobReference": {"projectId": "winter_test00", "jobId": "script_1_1"},
+							"statistics": {"creationTime": "1422943323002", "query": {
+								"statementType": "SELECT",
+								"totalBytesProcessed": "100",
+								"schema": {"fields": [{"name": "name", "type": "STRING"}]}
+							}}
+						},
+						{
+							"jobReference": {"projectId": "winter_test00", "jobId": "script_1_0"},
+							"statistics": {"creationTime": "1422943323001", "query": {
+								"statementType": "UPDATE",
+								"numDmlAffectedRows": "3",
+								"totalBytesProcessed": "200"
+							}}
+						}
+					]
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "script_1"},
+				"jobComplete": true,
+				"totalRows": "1",
+				"schema": {"fields": [{"name": "name", "type": "STRING"}]},
+				"rows": [{"f": [{"v": "test_name"}]}]
+			}`), nil
+		}))
+
+		Convey("When execute the script", func() {
+			results, err := c.Query("UPDATE test SET age = age + 1 WHERE true; SELECT name FROM test").ExecuteScript()
+
+			Convey("Then results of statements are returned in order", func() {
+				So(err, ShouldBeNil)
+				So(*sent.Configuration.Query.UseLegacySql, ShouldBeFalse)
+				So(parentJobID, ShouldEqual, "script_1")
+				So(len(results), ShouldEqual, 2)
+
+				So(results[0].JobID, ShouldEqual, "script_1_0")
+				So(results[0].StatementType, ShouldEqual, "UPDATE")
+				So(results[0].NumDmlAffectedRows, ShouldEqual, 3)
+				So(results[0].TotalBytesProcessed, ShouldEqual, 200)
+				So(results[0].Rows, ShouldBeNil)
+
+				So(results[1].JobID, ShouldEqual, "script_1_1")
+				So(results[1].StatementType, ShouldEqual, "SELECT")
+				So(results[1].Fields[0].Name, ShouldEqual, "name")
+
+				var res []nameRec
+				So(Convert(results[1].Fields, results[1].Rows, &res), ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name"}})
+			})
+		})
+	})
+
+	Convey("Given a client whose service fails a script", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{
+				"jobReference": {"projectId": "winter_test00", "jobId": "script_1"},
+				"status": {
+					"state": "DONE",
+					"errorResult": {"reason": "invalidQuery", "message": "Unrecognized name: nme"}
+				}
+			}`), nil
+		}))
+
+		Convey("When execute the script", func() {
+			_, err := c.Query("DECLARE x INT64; SELECT nme FROM test").ExecuteScript()
+
+			Convey("Then a job error is returned", func() {
+				jobErr, ok := err.(*JobError)
+				So(ok, ShouldBeTrue)
+				So(jobErr.Reason, ShouldEqual, "invalidQuery")
+			})
+		})
+	})
+}
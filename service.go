@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// QueryService is a subset of bigquery API used to run queries, manage their jobs and insert rows
+// A fake can be set by Client.WithService to test code using the client without bigquery
+// PatchTable is used to set the expiration of the temp table of a query
+type QueryService interface {
+	Query(projectID string, req *bigquery.QueryRequest) (*bigquery.QueryResponse, error)
+	InsertJob(projectID string, job *bigquery.Job) (*bigquery.Job, error)
+	GetJob(ref *bigquery.JobReference) (*bigquery.Job, error)
+	GetQueryResults(ref *bigquery.JobReference, opts QueryResultsOptions) (*bigquery.GetQueryResultsResponse, error)
+	CancelJob(ref *bigquery.JobReference) error
+	InsertAll(projectID, datasetID, tableID string, req *bigquery.TableDataInsertAllRequest) (*bigquery.TableDataInsertAllResponse, error)
+	PatchTable(projectID, datasetID, tableID string, table *bigquery.Table) (*bigquery.Table, error)
+}
+
+// QueryResultsOptions is options to get a page of query results
+// MaxResults is always sent to bigquery, while other options are not sent if they are zero
+type QueryResultsOptions struct {
+	PageToken  string
+	StartIndex uint64
+	MaxResults int64
+	TimeoutMs  int64
+}
+
+// WithService sets a service used to run queries and insert rows instead of bigquery API
+// Other operations like managing tables and datasets still require the service of bigquery API
+func (c *Client) WithService(svc QueryService) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queryService = svc
+	return c
+}
+
+// getQueryService returns the service set by WithService or the one calling bigquery API
+func (c *Client) getQueryService() (QueryService, error) {
+	return c.getQueryServiceContext(context.Background())
+}
+
+// getQueryServiceContext returns the service set by WithService or the one calling bigquery API with a given context
+func (c *Client) getQueryServiceContext(ctx context.Context) (QueryService, error) {
+	c.mu.Lock()
+	svc := c.queryService
+	c.mu.Unlock()
+	if svc != nil {
+		return svc, nil
+	}
+
+	service, err := c.getServiceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &apiService{service: service, ctx: ctx}, nil
+}
+
+// apiService is a QueryService calling bigquery API with a context
+// Job references passed to it have their locations resolved by the client
+type apiService struct {
+	service *bigquery.Service
+	ctx     context.Context
+}
+
+func (s *apiService) Query(projectID string, req *bigquery.QueryRequest) (*bigquery.QueryResponse, error) {
+	return s.service.Jobs.Query(projectID, req).Context(s.ctx).Do()
+}
+
+func (s *apiService) InsertJob(projectID string, job *bigquery.Job) (*bigquery.Job, error) {
+	return s.service.Jobs.Insert(projectID, job).Context(s.ctx).Do()
+}
+
+func (s *apiService) GetJob(ref *bigquery.JobReference) (*bigquery.Job, error) {
+	call := s.service.Jobs.Get(ref.ProjectId, ref.JobId).Context(s.ctx)
+	if ref.Location != "" {
+		call.Location(ref.Location)
+	}
+	return call.Do()
+}
+
+// CancelJob doesn't use the context since a job is canceled after the context is done
+func (s *apiService) CancelJob(ref *bigquery.JobReference) error {
+	call := s.service.Jobs.Cancel(ref.ProjectId, ref.JobId)
+	if ref.Location != "" {
+		call.Location(ref.Location)
+	}
+	_, err := call.Do()
+	return err
+}
+
+func (s *apiService) GetQueryResults(ref *bigquery.JobReference, opts QueryResultsOptions) (*bigquery.GetQueryResultsResponse, error) {
+	call := s.service.Jobs.GetQueryResults(ref.ProjectId, ref.JobId).Context(s.ctx)
+	if ref.Location != "" {
+		call.Location(ref.Location)
+	}
+	if opts.PageToken != "" {
+		call.PageToken(opts.PageToken)
+	}
+	if opts.StartIndex > 0 {
+		call.StartIndex(opts.StartIndex)
+	}
+	call.MaxResults(opts.MaxResults)
+	if opts.TimeoutMs > 0 {
+		call.TimeoutMs(opts.TimeoutMs)
+	}
+	return call.Do()
+}
+
+func (s *apiService) InsertAll(projectID, datasetID, tableID string, req *bigquery.TableDataInsertAllRequest) (*bigquery.TableDataInsertAllResponse, error) {
+	return s.service.Tabledata.InsertAll(projectID, datasetID, tableID, req).Context(s.ctx).Do()
+}
+
+func (s *apiService) PatchTable(projectID, datasetID, tableID string, table *bigquery.Table) (*bigquery.Table, error) {
+	return s.service.Tables.Patch(projectID, datasetID, tableID, table).Context(s.ctx).Do()
+}
+
+// locatedJob returns a copy of a given job reference with the location of the job resolved
+func (c *Client) locatedJob(ref *bigquery.JobReference) *bigquery.JobReference {
+	located := *ref
+	located.Location = c.jobLocation(ref)
+	return &located
+}
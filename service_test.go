@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// fakeService is a QueryService returning prepared responses without bigquery
+type fakeService struct {
+	queryResponse   *bigquery.QueryResponse
+	resultsResponse *bigquery.GetQueryResultsResponse
+	insertResponse  *bigquery.TableDataInsertAllResponse
+
+	queries  []*bigquery.QueryRequest
+	results  []QueryResultsOptions
+	inserted []*bigquery.TableDataInsertAllRequest
+	canceled []*bigquery.JobReference
+	gotJobs  []*bigquery.JobReference
+	patched  []*bigquery.Table
+}
+
+func (s *fakeService) Query(projectID string, req *bigquery.QueryRequest) (*bigquery.QueryResponse, error) {
+	s.queries = append(s.queries, req)
+	return s.queryResponse, nil
+}
+
+func (s *fakeService) InsertJob(projectID string, job *bigquery.Job) (*bigquery.Job, error) {
+	job.JobReference = &bigquery.JobReference{ProjectId: projectID, JobId: "job_1"}
+	job.Status = &bigquery.JobStatus{State: "DONE"}
+	return job, nil
+}
+
+func (s *fakeService) GetJob(ref *bigquery.JobReference) (*bigquery.Job, error) {
+	s.gotJobs = append(s.gotJobs, ref)
+	return &bigquery.Job{
+		JobReference: ref,
+		Status:       &bigquery.JobStatus{State: "DONE"},
+		Statistics:   &bigquery.JobStatistics{Query: &bigquery.JobStatistics2{TotalBytesProcessed: 1024}},
+	}, nil
+}
+
+func (s *fakeService) GetQueryResults(ref *bigquery.JobReference, opts QueryResultsOptions) (*bigquery.GetQueryResultsResponse, error) {
+	s.results = append(s.results, opts)
+	return s.resultsResponse, nil
+}
+
+func (s *fakeService) CancelJob(ref *bigquery.JobReference) error {
+	s.canceled = append(s.canceled, ref)
+	return nil
+}
+
+func (s *fakeService) InsertAll(projectID, datasetID, tableID string, req *bigquery.TableDataInsertAllRequest) (*bigquery.TableDataInsertAllResponse, error) {
+	s.inserted = append(s.inserted, req)
+	if s.insertResponse == nil {
+		return &bigquery.TableDataInsertAllResponse{}, nil
+	}
+	return s.insertResponse, nil
+}
+
+func (s *fakeService) PatchTable(projectID, datasetID, tableID string, table *bigquery.Table) (*bigquery.Table, error) {
+	s.patched = append(s.patched, table)
+	return table, nil
+}
+
+func TestWithService(t *testing.T) {
+	schema := &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{{Name: "name", Type: "STRING"}}}
+
+	Convey("Given a client with a fake service", t, func() {
+		svc := &fakeService{
+			queryResponse: &bigquery.QueryResponse{
+				JobReference: &bigquery.JobReference{ProjectId: "winter_test00", JobId: "job_1"},
+				JobComplete:  true,
+				TotalRows:    2,
+				Schema:       schema,
+				Rows:         []*bigquery.TableRow{{F: []*bigquery.TableCell{{V: "test_name1"}}}},
+				PageToken:    "page_1",
+			},
+			resultsResponse: &bigquery.GetQueryResultsResponse{
+				JobReference: &bigquery.JobReference{ProjectId: "winter_test00", JobId: "job_1"},
+				JobComplete:  true,
+				TotalRows:    2,
+				Schema:       schema,
+				Rows:         []*bigquery.TableRow{{F: []*bigquery.TableCell{{V: "test_name2"}}}},
+			},
+		}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+		c.Dataset("winter_test00", "bq_test")
+		c.WithService(svc)
+
+		Convey("When execute a query", func() {
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").SetPageSize(1).Execute(&res)
+
+			Convey("Then rows are fetched from the fake service", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name1"}, {Name: "test_name2"}})
+				So(svc.queries, ShouldHaveLength, 1)
+				So(svc.queries[0].Query, ShouldEqual, "SELECT name FROM test")
+				So(svc.results, ShouldHaveLength, 1)
+				So(svc.results[0].PageToken, ShouldEqual, "page_1")
+				So(svc.results[0].MaxResults, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When insert rows", func() {
+			rows := []map[string]interface{}{{"name": "test_name1"}, {"name": "test_name2"}}
+			err := c.InsertRowsByJSON("test_table", rows)
+
+			Convey("Then rows are sent to the fake service", func() {
+				So(err, ShouldBeNil)
+				So(svc.inserted, ShouldHaveLength, 1)
+				So(svc.inserted[0].Rows, ShouldHaveLength, 2)
+				So(svc.inserted[0].Rows[1].Json["name"], ShouldEqual, "test_name2")
+			})
+		})
+
+		Convey("When insert rows rejected by the fake service", func() {
+			svc.insertResponse = &bigquery.TableDataInsertAllResponse{
+				InsertErrors: []*bigquery.TableDataInsertAllResponseInsertErrors{
+					{Index: 1, Errors: []*bigquery.ErrorProto{{Reason: "invalid", Message: "no such field"}}},
+				},
+			}
+			err := c.InsertRowsByJSON("test_table", []map[string]interface{}{{"name": "test_name1"}, {"unknown": 1}})
+
+			Convey("Then the insert error is returned", func() {
+				So(err, ShouldNotBeNil)
+				_, ok := err.(*InsertError)
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestWithServiceJobs(t *testing.T) {
+	Convey("Given a client with a fake service and no valid credentials", t, func() {
+		svc := &fakeService{
+			resultsResponse: &bigquery.GetQueryResultsResponse{
+				JobReference: &bigquery.JobReference{ProjectId: "winter_test00", JobId: "job_1"},
+				JobComplete:  true,
+				TotalRows:    1,
+				Schema:       &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{{Name: "name", Type: "STRING"}}},
+				Rows:         []*bigquery.TableRow{{F: []*bigquery.TableCell{{V: "test_name1"}}}},
+			},
+		}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+		c.Dataset("winter_test00", "bq_test")
+		c.WithService(svc)
+
+		Convey("When manage a submitted job", func() {
+			job, err := c.Query("SELECT name FROM test").SubmitJob()
+			So(err, ShouldBeNil)
+			state, statusErr := job.Status()
+			waitErr := job.Wait(context.Background())
+			cancelErr := job.Cancel()
+
+			Convey("Then the job is managed by the fake service", func() {
+				So(statusErr, ShouldBeNil)
+				So(state, ShouldEqual, "DONE")
+				So(waitErr, ShouldBeNil)
+				So(cancelErr, ShouldBeNil)
+				So(svc.gotJobs, ShouldHaveLength, 2)
+				So(svc.canceled, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When get stats of a query with an expiring temp table", func() {
+			var res []nameRec
+			q := c.Query("SELECT name FROM test").SetJobConfig(&JobConfiguration{
+				TempTableName:       "temp_table",
+				TempTableExpiration: time.Hour,
+			})
+			err := q.Execute(&res)
+			So(err, ShouldBeNil)
+			stats, err := q.Stats()
+
+			Convey("Then stats and the expiration are sent to the fake service", func() {
+				So(err, ShouldBeNil)
+				So(stats.TotalBytesProcessed, ShouldEqual, 1024)
+				So(svc.patched, ShouldHaveLength, 1)
+				So(svc.patched[0].ExpirationTime, ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+}
+
+func TestExecuteWithDeadline(t *testing.T) {
+	Convey("Given a client with a fake service whose query keeps running", t, func() {
+		running := &bigquery.GetQueryResultsResponse{
+			JobReference: &bigquery.JobReference{ProjectId: "winter_test00", JobId: "job_1"},
+			JobComplete:  false,
+		}
+		svc := &fakeService{
+			queryResponse: &bigquery.QueryResponse{
+				JobReference: running.JobReference,
+				JobComplete:  false,
+			},
+			resultsResponse: running,
+		}
+		c := New("example@gmail.com", []byte("this is test pem dummy"), "")
+		c.Dataset("winter_test00", "bq_test")
+		c.WithService(svc)
+		c.pollInterval = 5 * time.Millisecond
+
+		Convey("When execute a query with a deadline expiring during polling", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+			defer cancel()
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").ExecuteWithDeadline(ctx, &res)
+
+			Convey("Then the job is canceled and the error of the context is returned", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+				So(svc.results, ShouldNotBeEmpty)
+				So(svc.canceled, ShouldHaveLength, 1)
+				So(svc.canceled[0].JobId, ShouldEqual, "job_1")
+			})
+		})
+
+		Convey("When execute a query with a deadline after the query completes", func() {
+			svc.resultsResponse = &bigquery.GetQueryResultsResponse{
+				JobReference: running.JobReference,
+				JobComplete:  true,
+				TotalRows:    1,
+				Schema:       &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{{Name: "name", Type: "STRING"}}},
+				Rows:         []*bigquery.TableRow{{F: []*bigquery.TableCell{{V: "test_name1"}}}},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			var res []nameRec
+			err := c.Query("SELECT name FROM test").ExecuteWithDeadline(ctx, &res)
+
+			Convey("Then results are returned without canceling the job", func() {
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, []nameRec{{Name: "test_name1"}})
+				So(svc.canceled, ShouldBeEmpty)
+			})
+		})
+	})
+}
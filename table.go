@@ -0,0 +1,482 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+const (
+	fieldModeNullable = "NULLABLE"
+	fieldModeRequired = "REQUIRED"
+	fieldModeRepeated = "REPEATED"
+)
+
+// PartitionType is a unit of time to partition a table
+type PartitionType string
+
+const (
+	// PartitionDay is option to partition a table by day
+	PartitionDay PartitionType = "DAY"
+	// PartitionHour is option to partition a table by hour
+	PartitionHour PartitionType = "HOUR"
+	// PartitionMonth is option to partition a table by month
+	PartitionMonth PartitionType = "MONTH"
+	// PartitionYear is option to partition a table by year
+	PartitionYear PartitionType = "YEAR"
+)
+
+// TimePartitioning partitions a table by time
+// A table is partitioned by ingestion time without Field, and partitions don't expire without Expiration
+type TimePartitioning struct {
+	Type       PartitionType
+	Field      string
+	Expiration time.Duration
+}
+
+// TableOptions is options to create a table
+// Clustering is names of columns to cluster a table by, and each of them must be in the schema
+// MaxStaleness is how stale data read from the table can be, which bigquery accepts for tables using change data capture
+// It is only set to a table since bigquery API has no max staleness for a query
+type TableOptions struct {
+	TimePartitioning *TimePartitioning
+	Clustering       []string
+	MaxStaleness     time.Duration
+}
+
+// timePartitioning converts the partitioning into a bigquery one
+func (p *TimePartitioning) timePartitioning() *bigquery.TimePartitioning {
+	if p == nil {
+		return nil
+	}
+	return &bigquery.TimePartitioning{
+		Type:         string(p.Type),
+		Field:        p.Field,
+		ExpirationMs: int64(p.Expiration / time.Millisecond),
+	}
+}
+
+// stalenessInterval formats a given duration as an INTERVAL like "0-0 1 2:30:0" for max staleness
+// It returns an empty string for a zero duration to leave the staleness unset
+func stalenessInterval(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+	d -= seconds * time.Second
+
+	interval := fmt.Sprintf("0-0 %d %d:%d:%d", days, hours, minutes, seconds)
+	if micros := d / time.Microsecond; micros > 0 {
+		interval += fmt.Sprintf(".%06d", micros)
+	}
+	return interval
+}
+
+// clustering converts given column names into bigquery clustering
+func clustering(fields []string) *bigquery.Clustering {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &bigquery.Clustering{Fields: fields}
+}
+
+// validateClustering checks that all clustering fields are columns of a given schema
+func validateClustering(clusteringFields []string, fields []*bigquery.TableFieldSchema) error {
+	for _, name := range clusteringFields {
+		var found bool
+		for _, field := range fields {
+			if strings.EqualFold(field.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unknown clustering field: %s", name)
+		}
+	}
+	return nil
+}
+
+// CreateTable creates a new table with schema inferred from a given struct
+// A bq tag like `bq:"name,required"` controls a column name and REQUIRED mode
+// string -> STRING
+// int, int8, int16, int32, int64, uint, uint8, uint16, uint32 -> INTEGER
+// float32, float64 -> FLOAT
+// bool -> BOOLEAN
+// time.Time -> TIMESTAMP
+// []byte -> BYTES
+// struct -> RECORD
+// slice -> REPEATED
+func (c *Client) CreateTable(tableID string, schema interface{}) error {
+	return c.CreateTableWithOptions(tableID, schema, TableOptions{})
+}
+
+// CreateTableWithOptions creates a new table with schema inferred from a given struct and given options
+func (c *Client) CreateTableWithOptions(tableID string, schema interface{}, opts TableOptions) error {
+	fields, err := inferSchema(schema)
+	if err != nil {
+		return err
+	}
+	if err := validateClustering(opts.Clustering, fields); err != nil {
+		return err
+	}
+
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return err
+	}
+
+	table := &bigquery.Table{
+		TableReference: &bigquery.TableReference{
+			ProjectId: ref.ProjectId,
+			DatasetId: ref.DatasetId,
+			TableId:   tableID,
+		},
+		Schema: &bigquery.TableSchema{
+			Fields: fields,
+		},
+		TimePartitioning: opts.TimePartitioning.timePartitioning(),
+		Clustering:       clustering(opts.Clustering),
+		MaxStaleness:     stalenessInterval(opts.MaxStaleness),
+	}
+
+	return c.do(func() error {
+		_, err := service.Tables.Insert(ref.ProjectId, ref.DatasetId, table).Do()
+		return err
+	})
+}
+
+// DeleteTable deletes a table in the dataset
+func (c *Client) DeleteTable(tableID string) error {
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return err
+	}
+
+	return c.do(func() error {
+		return service.Tables.Delete(ref.ProjectId, ref.DatasetId, tableID).Do()
+	})
+}
+
+// TableExists reports whether a table exists in the dataset
+func (c *Client) TableExists(tableID string) (bool, error) {
+	ref, err := c.dataset()
+	if err != nil {
+		return false, err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return false, err
+	}
+
+	err = c.do(func() error {
+		_, err := service.Tables.Get(ref.ProjectId, ref.DatasetId, tableID).Do()
+		return err
+	})
+	if err != nil {
+		if apiErr, ok := err.(*APIError); ok && apiErr.IsNotFound() {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// TableRowCount returns the number of rows of a table from its metadata without scanning it
+// Rows in the streaming buffer may not be counted yet until they are committed to the table
+func (c *Client) TableRowCount(tableID string) (uint64, error) {
+	ref, err := c.dataset()
+	if err != nil {
+		return 0, err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return 0, err
+	}
+
+	var table *bigquery.Table
+	err = c.do(func() (err error) {
+		table, err = service.Tables.Get(ref.ProjectId, ref.DatasetId, tableID).Do()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return table.NumRows, nil
+}
+
+// TableIsEmpty reports whether a table has no rows from its metadata
+// A table with rows only in the streaming buffer may be reported as empty
+func (c *Client) TableIsEmpty(tableID string) (bool, error) {
+	count, err := c.TableRowCount(tableID)
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// ListTables lists IDs of all tables in the dataset
+func (c *Client) ListTables() ([]string, error) {
+	tables, err := c.ListTableEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	tableIDs := make([]string, 0, len(tables))
+	for i := range tables {
+		tableIDs = append(tableIDs, tables[i].TableReference.TableId)
+	}
+	return tableIDs, nil
+}
+
+// ListTableEntries lists all tables in the dataset with metadata like creation time
+func (c *Client) ListTableEntries() ([]*bigquery.TableListTables, error) {
+	ref, err := c.dataset()
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []*bigquery.TableListTables
+	var pageToken string
+	for {
+		call := service.Tables.List(ref.ProjectId, ref.DatasetId)
+		if len(pageToken) != 0 {
+			call.PageToken(pageToken)
+		}
+
+		var list *bigquery.TableList
+		err = c.do(func() (err error) {
+			list, err = call.Do()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		tables = append(tables, list.Tables...)
+		if len(list.NextPageToken) == 0 {
+			return tables, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
+
+// ReadTable reads all rows of a table without a query and converts them into a given result
+// Reading a table directly doesn't incur query costs
+func (c *Client) ReadTable(tableID string, result interface{}) error {
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return err
+	}
+
+	var table *bigquery.Table
+	err = c.do(func() (err error) {
+		table, err = service.Tables.Get(ref.ProjectId, ref.DatasetId, tableID).Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var rows []*bigquery.TableRow
+	var pageToken string
+	for {
+		call := service.Tabledata.List(ref.ProjectId, ref.DatasetId, tableID).MaxResults(defaultPageSize)
+		if len(pageToken) != 0 {
+			call.PageToken(pageToken)
+		}
+
+		var list *bigquery.TableDataList
+		err = c.do(func() (err error) {
+			list, err = call.Do()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, list.Rows...)
+		if len(list.PageToken) == 0 {
+			break
+		}
+		pageToken = list.PageToken
+	}
+
+	var fields []*bigquery.TableFieldSchema
+	if table.Schema != nil {
+		fields = table.Schema.Fields
+	}
+	return c.convert(fields, rows, result)
+}
+
+// AddColumns adds given columns to the schema of an existing table
+// BigQuery doesn't allow adding REQUIRED columns, so new columns must be NULLABLE or REPEATED
+func (c *Client) AddColumns(tableID string, newFields []*bigquery.TableFieldSchema) error {
+	for _, field := range newFields {
+		if field.Mode == fieldModeRequired {
+			return fmt.Errorf("Cannot add REQUIRED column %s: new columns must be NULLABLE", field.Name)
+		}
+	}
+
+	ref, err := c.dataset()
+	if err != nil {
+		return err
+	}
+
+	service, err := c.getService()
+	if err != nil {
+		return err
+	}
+
+	var table *bigquery.Table
+	err = c.do(func() (err error) {
+		table, err = service.Tables.Get(ref.ProjectId, ref.DatasetId, tableID).Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var fields []*bigquery.TableFieldSchema
+	if table.Schema != nil {
+		fields = table.Schema.Fields
+	}
+	for _, newField := range newFields {
+		for _, field := range fields {
+			if strings.EqualFold(field.Name, newField.Name) {
+				return fmt.Errorf("Column %s already exists", newField.Name)
+			}
+		}
+	}
+
+	patch := &bigquery.Table{
+		Schema: &bigquery.TableSchema{
+			Fields: append(fields, newFields...),
+		},
+	}
+	return c.do(func() error {
+		_, err := service.Tables.Patch(ref.ProjectId, ref.DatasetId, tableID, patch).Do()
+		return err
+	})
+}
+
+// inferSchema builds bigquery table fields from a given struct or pointer to struct
+func inferSchema(schema interface{}) ([]*bigquery.TableFieldSchema, error) {
+	t := reflect.TypeOf(schema)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("Not struct")
+	}
+	return inferFields(t)
+}
+
+func inferFields(t reflect.Type) ([]*bigquery.TableFieldSchema, error) {
+	fields := make([]*bigquery.TableFieldSchema, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := columnName(f)
+		if !ok {
+			continue
+		}
+
+		field, err := inferField(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		field.Name = name
+		if field.Mode == "" {
+			field.Mode = fieldModeNullable
+			if hasTagOption(f, "required") {
+				field.Mode = fieldModeRequired
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func inferField(t reflect.Type) (*bigquery.TableFieldSchema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &bigquery.TableFieldSchema{Type: fieldTypeTimestamp}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &bigquery.TableFieldSchema{Type: fieldTypeString}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &bigquery.TableFieldSchema{Type: fieldTypeInteger}, nil
+	case reflect.Float32, reflect.Float64:
+		return &bigquery.TableFieldSchema{Type: fieldTypeFloat}, nil
+	case reflect.Bool:
+		return &bigquery.TableFieldSchema{Type: fieldTypeBoolean}, nil
+	case reflect.Struct:
+		fields, err := inferFields(t)
+		if err != nil {
+			return nil, err
+		}
+		return &bigquery.TableFieldSchema{Type: fieldTypeRecord, Fields: fields}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &bigquery.TableFieldSchema{Type: fieldTypeBytes}, nil
+		}
+		if t.Elem().Kind() == reflect.Slice {
+			return nil, errors.New("Nested repeated field is not supported")
+		}
+		field, err := inferField(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		field.Mode = fieldModeRepeated
+		return field, nil
+	}
+	return nil, errors.New("Unsupported field type: " + t.String())
+}
+
+// hasTagOption reports whether a bq tag of a given struct field has an option
+func hasTagOption(f reflect.StructField, option string) bool {
+	options := strings.Split(f.Tag.Get("bq"), ",")
+	for _, o := range options[1:] {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,413 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+type tableAddress struct {
+	City string
+	Zip  string `bq:"zip_code"`
+}
+
+type tableRec struct {
+	ID        int64 `bq:"id,required"`
+	Name      string
+	Score     float64
+	IsDeleted bool
+	CreatedAt time.Time
+	Tags      []string
+	Address   tableAddress
+	Histories []tableAddress
+	Ignored   string `bq:"-"`
+}
+
+func TestCreateTable(t *testing.T) {
+	Convey("Given a client whose service creates a table", t, func() {
+		var sent bigquery.Table
+		var path string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			path = req.URL.Path
+			json.NewDecoder(req.Body).Decode(&sent)
+			return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test.test_table"}`), nil
+		}))
+
+		Convey("When create a table from a struct", func() {
+			err := c.CreateTable("test_table", tableRec{})
+
+			Convey("Then a table is created with inferred schema", func() {
+				So(err, ShouldBeNil)
+				So(path, ShouldEqual, "/bigquery/v2/projects/winter_test00/datasets/bq_test/tables")
+				So(sent.TableReference.TableId, ShouldEqual, "test_table")
+
+				address := []*bigquery.TableFieldSchema{
+					{Name: "City", Type: "STRING", Mode: "NULLABLE"},
+					{Name: "zip_code", Type: "STRING", Mode: "NULLABLE"},
+				}
+				So(sent.Schema.Fields, ShouldResemble, []*bigquery.TableFieldSchema{
+					{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+					{Name: "Name", Type: "STRING", Mode: "NULLABLE"},
+					{Name: "Score", Type: "FLOAT", Mode: "NULLABLE"},
+					{Name: "IsDeleted", Type: "BOOLEAN", Mode: "NULLABLE"},
+					{Name: "CreatedAt", Type: "TIMESTAMP", Mode: "NULLABLE"},
+					{Name: "Tags", Type: "STRING", Mode: "REPEATED"},
+					{Name: "Address", Type: "RECORD", Mode: "NULLABLE", Fields: address},
+					{Name: "Histories", Type: "RECORD", Mode: "REPEATED", Fields: address},
+				})
+			})
+		})
+
+		Convey("When create a table from a non struct", func() {
+			err := c.CreateTable("test_table", "test")
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Not struct")
+			})
+		})
+
+		Convey("When create a table with time partitioning", func() {
+			err := c.CreateTableWithOptions("test_table", tableRec{}, TableOptions{
+				TimePartitioning: &TimePartitioning{Type: PartitionDay, Field: "CreatedAt", Expiration: 48 * time.Hour},
+			})
+
+			Convey("Then a table is created with the partitioning", func() {
+				So(err, ShouldBeNil)
+				So(sent.TimePartitioning, ShouldResemble, &bigquery.TimePartitioning{
+					Type:         "DAY",
+					Field:        "CreatedAt",
+					ExpirationMs: 172800000,
+				})
+			})
+		})
+
+		Convey("When create a table with clustering fields", func() {
+			err := c.CreateTableWithOptions("test_table", tableRec{}, TableOptions{
+				Clustering: []string{"Name", "id"},
+			})
+
+			Convey("Then a table is created with the clustering", func() {
+				So(err, ShouldBeNil)
+				So(sent.Clustering, ShouldResemble, &bigquery.Clustering{Fields: []string{"Name", "id"}})
+			})
+		})
+
+		Convey("When create a table with max staleness", func() {
+			err := c.CreateTableWithOptions("test_table", tableRec{}, TableOptions{
+				MaxStaleness: 26*time.Hour + 30*time.Minute + 1500*time.Millisecond,
+			})
+
+			Convey("Then a table is created with the staleness as an interval", func() {
+				So(err, ShouldBeNil)
+				So(sent.MaxStaleness, ShouldEqual, "0-0 1 2:30:1.500000")
+			})
+		})
+
+		Convey("When create a table with an unknown clustering field", func() {
+			err := c.CreateTableWithOptions("test_table", tableRec{}, TableOptions{
+				Clustering: []string{"unknown"},
+			})
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Unknown clustering field: unknown")
+			})
+		})
+	})
+}
+
+func TestDeleteTable(t *testing.T) {
+	Convey("Given a client whose service deletes a table", t, func() {
+		var method, path string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			method, path = req.Method, req.URL.Path
+			return jsonResponse(http.StatusNoContent, ``), nil
+		}))
+
+		Convey("When delete a table", func() {
+			err := c.DeleteTable("test_table")
+
+			Convey("Then the table is deleted", func() {
+				So(err, ShouldBeNil)
+				So(method, ShouldEqual, http.MethodDelete)
+				So(path, ShouldEqual, "/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table")
+			})
+		})
+	})
+
+	Convey("Given a client without a dataset", t, func() {
+		var count int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			count++
+			return jsonResponse(http.StatusNoContent, ``), nil
+		}))
+		c.datasetRef = nil
+
+		Convey("When delete a table", func() {
+			err := c.DeleteTable("test_table")
+
+			Convey("Then err is returned without sending a request", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "No dataset")
+				So(count, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When create a table", func() {
+			err := c.CreateTable("test_table", struct{ Name string }{})
+
+			Convey("Then err is returned without sending a request", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "No dataset")
+				So(count, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestTableExists(t *testing.T) {
+	Convey("Given a client whose service has a table", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test.test_table"}`), nil
+		}))
+
+		Convey("When check the table exists", func() {
+			exists, err := c.TableExists("test_table")
+
+			Convey("Then true is returned", func() {
+				So(err, ShouldBeNil)
+				So(exists, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a client whose service has no table", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusNotFound, `{"error": {"code": 404, "message": "Not found: Table"}}`), nil
+		}))
+
+		Convey("When check the table exists", func() {
+			exists, err := c.TableExists("test_table")
+
+			Convey("Then false is returned", func() {
+				So(err, ShouldBeNil)
+				So(exists, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a client whose service denies access", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusForbidden, `{"error": {"code": 403, "message": "Access Denied"}}`), nil
+		}))
+
+		Convey("When check the table exists", func() {
+			exists, err := c.TableExists("test_table")
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(exists, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestTableRowCount(t *testing.T) {
+	Convey("Given a client whose service has a table with rows", t, func() {
+		var path string
+		numRows := "1234"
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			path = req.URL.Path
+			return jsonResponse(http.StatusOK, `{"id": "winter_test00:bq_test.test_table", "numRows": "`+numRows+`"}`), nil
+		}))
+
+		Convey("When count rows of the table", func() {
+			count, err := c.TableRowCount("test_table")
+
+			Convey("Then the number of rows in the metadata is returned", func() {
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 1234)
+				So(path, ShouldEqual, "/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table")
+			})
+		})
+
+		Convey("When check the table is empty", func() {
+			empty, err := c.TableIsEmpty("test_table")
+
+			Convey("Then false is returned", func() {
+				So(err, ShouldBeNil)
+				So(empty, ShouldBeFalse)
+			})
+		})
+
+		Convey("When check the table without rows is empty", func() {
+			numRows = "0"
+			empty, err := c.TableIsEmpty("test_table")
+
+			Convey("Then true is returned", func() {
+				So(err, ShouldBeNil)
+				So(empty, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a client whose service has no table", t, func() {
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusNotFound, `{"error": {"code": 404, "message": "Not found: Table"}}`), nil
+		}))
+
+		Convey("When check the table is empty", func() {
+			_, err := c.TableIsEmpty("test_table")
+
+			Convey("Then err is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.(*APIError).IsNotFound(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestListTables(t *testing.T) {
+	Convey("Given a client whose service lists tables in two pages", t, func() {
+		var pageTokens []string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			pageToken := req.URL.Query().Get("pageToken")
+			pageTokens = append(pageTokens, pageToken)
+			if pageToken == "" {
+				return jsonResponse(http.StatusOK, `{
+					"tables": [{"tableReference": {"tableId": "table_1"}, "creationTime": "1422943323461"}],
+					"nextPageToken": "page_2"
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"tables": [{"tableReference": {"tableId": "table_2"}, "creationTime": "1422943323462"}]
+			}`), nil
+		}))
+
+		Convey("When list tables", func() {
+			tableIDs, err := c.ListTables()
+
+			Convey("Then IDs of all tables are returned", func() {
+				So(err, ShouldBeNil)
+				So(pageTokens, ShouldResemble, []string{"", "page_2"})
+				So(tableIDs, ShouldResemble, []string{"table_1", "table_2"})
+			})
+		})
+
+		Convey("When list table entries", func() {
+			tables, err := c.ListTableEntries()
+
+			Convey("Then all tables with metadata are returned", func() {
+				So(err, ShouldBeNil)
+				So(len(tables), ShouldEqual, 2)
+				So(tables[1].TableReference.TableId, ShouldEqual, "table_2")
+				So(tables[1].CreationTime, ShouldEqual, 1422943323462)
+			})
+		})
+	})
+}
+
+func TestReadTable(t *testing.T) {
+	Convey("Given a client whose service has table data in two pages", t, func() {
+		var paths []string
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path+"?"+req.URL.Query().Get("pageToken"))
+			if !strings.HasSuffix(req.URL.Path, "/data") {
+				return jsonResponse(http.StatusOK, `{
+					"tableReference": {"projectId": "winter_test00", "datasetId": "bq_test", "tableId": "test_table"},
+					"schema": {"fields": [{"name": "name", "type": "STRING"}]}
+				}`), nil
+			}
+			if req.URL.Query().Get("pageToken") == "" {
+				return jsonResponse(http.StatusOK, `{
+					"totalRows": "2",
+					"rows": [{"f": [{"v": "test_name1"}]}],
+					"pageToken": "page_2"
+				}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{
+				"totalRows": "2",
+				"rows": [{"f": [{"v": "test_name2"}]}]
+			}`), nil
+		}))
+
+		Convey("When read the table", func() {
+			var res []nameRec
+			err := c.ReadTable("test_table", &res)
+
+			Convey("Then all rows are converted", func() {
+				So(err, ShouldBeNil)
+				So(paths, ShouldResemble, []string{
+					"/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table?",
+					"/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table/data?",
+					"/bigquery/v2/projects/winter_test00/datasets/bq_test/tables/test_table/data?page_2",
+				})
+				So(res, ShouldResemble, []nameRec{{Name: "test_name1"}, {Name: "test_name2"}})
+			})
+		})
+	})
+}
+
+func TestAddColumns(t *testing.T) {
+	Convey("Given a client whose service has a table", t, func() {
+		var patched bigquery.Table
+		var patches int
+		c := newFakeClient(fakeTransport(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPatch {
+				patches++
+				json.NewDecoder(req.Body).Decode(&patched)
+			}
+			return jsonResponse(http.StatusOK, `{
+				"tableReference": {"projectId": "winter_test00", "datasetId": "bq_test", "tableId": "test_table"},
+				"schema": {"fields": [{"name": "name", "type": "STRING", "mode": "REQUIRED"}]}
+			}`), nil
+		}))
+
+		Convey("When add nullable columns", func() {
+			err := c.AddColumns("test_table", []*bigquery.TableFieldSchema{
+				{Name: "age", Type: "INTEGER", Mode: "NULLABLE"},
+				{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+			})
+
+			Convey("Then the merged schema is patched", func() {
+				So(err, ShouldBeNil)
+				So(patches, ShouldEqual, 1)
+				So(patched.Schema.Fields, ShouldResemble, []*bigquery.TableFieldSchema{
+					{Name: "name", Type: "STRING", Mode: "REQUIRED"},
+					{Name: "age", Type: "INTEGER", Mode: "NULLABLE"},
+					{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+				})
+			})
+		})
+
+		Convey("When add a required column", func() {
+			err := c.AddColumns("test_table", []*bigquery.TableFieldSchema{
+				{Name: "age", Type: "INTEGER", Mode: "REQUIRED"},
+			})
+
+			Convey("Then err is returned without patch", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Cannot add REQUIRED column age: new columns must be NULLABLE")
+				So(patches, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When add an existing column", func() {
+			err := c.AddColumns("test_table", []*bigquery.TableFieldSchema{
+				{Name: "name", Type: "STRING"},
+			})
+
+			Convey("Then err is returned without patch", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Column name already exists")
+				So(patches, ShouldEqual, 0)
+			})
+		})
+	})
+}